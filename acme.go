@@ -13,7 +13,6 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
@@ -34,6 +33,9 @@ var (
 	mtpt              = flag.String("m", defaultMtpt, "Mountpoint for 9P file server")
 	swapScrollButtons = flag.Bool("r", false, "Swap scroll buttons")
 	winsize           = flag.String("W", "", "Window size and position as WidthxHeight[@X,Y]")
+	maxErrLines       = flag.Int("E", 0, "Maximum number of lines kept in +Errors windows (0 means no limit)")
+	headlessflag      = flag.Bool("headless", false, "Run without a display, serving only the 9P file system")
+	primaryflag       = flag.Bool("primary", false, "Mirror selections made with button 1 into the PRIMARY selection")
 )
 
 func main() {
@@ -59,26 +61,19 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not get user home directory: %v", err)
 	}
-	acmeshell = os.Getenv("acmeshell")
-	p := os.Getenv("tabstop")
-	if p != "" {
-		mt, _ := strconv.ParseInt(p, 10, 32)
-		maxtab = uint(mt)
-	}
-	if maxtab == 0 {
-		maxtab = 4
-	}
-
-	b := os.Getenv("tabexpand")
-	if b != "" {
-		te, _ := strconv.ParseBool(b)
-		tabexpand = te
-	} else {
-		tabexpand = false
-	}
+	loadconfig()
 
 	var dump *dumpfile.Content
 
+	// With nothing else to open, restore the session of the project
+	// we're in, and save it again when we exit.
+	if dir, err := os.Getwd(); err == nil && loadfile == "" && flag.NArg() == 0 {
+		session = projectsession(dir)
+		if session != "" && access(sessionfile(session)) {
+			loadfile = sessionfile(session)
+		}
+	}
+
 	if loadfile != "" {
 		d, err := dumpfile.Load(loadfile) // Overrides fonts selected up to here.
 		if err != nil {
@@ -104,51 +99,66 @@ func main() {
 
 	wdir, _ = os.Getwd()
 
-	draw.Main(func(dd *draw.Device) {
-		drawDev = dd
-		tagfont = *varfontflag
-
-		cwait = make(chan ProcessState)
-		ccommand = make(chan *Command)
-		ckill = make(chan string)
-		cxfidalloc = make(chan *Xfid)
-		cxfidfree = make(chan *Xfid)
-		cnewwindow = make(chan *Window)
-		csignal = make(chan os.Signal, 1)
-		cerr = make(chan error)
-		cedit = make(chan int)
-		cexit = make(chan struct{})
-		cwarn = make(chan uint)
+	if *headlessflag {
+		mainloop(nil, dump, loadfile)
+	} else {
+		draw.Main(func(dd *draw.Device) {
+			mainloop(dd, dump, loadfile)
+		})
+	}
+}
 
-		startplumbing()
-		fs := fsysinit()
+// mainloop initializes the row and the file server, and runs until Edward
+// exits. In headless mode, dd is nil and windows are given displays not
+// connected to any window system.
+func mainloop(dd *draw.Device, dump *dumpfile.Content, loadfile string) {
+	drawDev = dd
+	tagfont = *varfontflag
+
+	cwait = make(chan ProcessState)
+	ccommand = make(chan *Command)
+	ckill = make(chan string)
+	cxfidalloc = make(chan *Xfid)
+	cxfidfree = make(chan *Xfid)
+	cnewwindow = make(chan *Window)
+	csignal = make(chan os.Signal, 1)
+	cerr = make(chan error)
+	cedit = make(chan int)
+	cexit = make(chan struct{})
+	cwarn = make(chan uint)
+
+	startplumbing()
+	if *primaryflag && !*headlessflag {
+		startprimary()
+	}
+	fs := fsysinit()
 
-		// disk = NewDisk()  TODO(flux): Let's be sure we'll avoid this paging stuff
+	// disk = NewDisk()  TODO(flux): Let's be sure we'll avoid this paging stuff
 
-		const WindowsPerCol = 6
+	const WindowsPerCol = 6
 
-		row.Init(dump, loadfile)
+	loadscripts(scriptdir())
+	row.Init(dump, loadfile)
 
-		// After row is initialized
-		ctx := context.Background()
-		go waitthread(ctx)
-		go newwindowthread()
-		go xfidallocthread(ctx)
+	// After row is initialized
+	ctx := context.Background()
+	go waitthread(ctx)
+	go newwindowthread()
+	go xfidallocthread(ctx)
 
-		signal.Ignore(ignoreSignals...)
-		signal.Notify(csignal, hangupSignals...)
+	signal.Ignore(ignoreSignals...)
+	signal.Notify(csignal, hangupSignals...)
 
-		select {
-		case <-cexit:
-			// Do nothing.
-		case <-csignal:
-			row.lk.Lock()
-			row.Dump("")
-			row.lk.Unlock()
-		}
-		killprocs(fs)
-		os.Exit(0)
-	})
+	select {
+	case <-cexit:
+		// Do nothing.
+	case <-csignal:
+		row.lk.Lock()
+		autosave()
+		row.lk.Unlock()
+	}
+	killprocs(fs)
+	os.Exit(0)
 }
 
 // readArgFiles opens the files from the command line.
@@ -211,6 +221,7 @@ func iconinit(display draw.Display, m *iconImages, fontget func(string) draw.Fon
 		m.textcolors[frame.ColBord], _ = display.AllocImage(image.Rect(0, 0, 1, 1), display.ScreenImage().Pix(), true, draw.Yellowgreen)
 		m.textcolors[frame.ColText] = display.Black()
 		m.textcolors[frame.ColHText] = display.Black()
+		configcolors(display, m)
 	}
 
 	// ...
@@ -259,6 +270,7 @@ func mousethread(w *Window) {
 		var ok bool
 		row.lk.Lock()
 		flushwarnings()
+		flushsnarfs()
 		row.lk.Unlock()
 		display.Flush()
 		select {
@@ -628,20 +640,3 @@ func (w errorWriter) Write(data []byte) (n int, err error) {
 func (w errorWriter) Close() error {
 	return nil
 }
-
-const MAXSNARF = 100 * 1024
-
-func acmeputsnarf(display draw.Display, snarf Buffer) {
-	r := make([]rune, snarf.nc())
-	snarf.Read(0, r[:snarf.nc()])
-	display.WriteSnarf([]byte(string(r)))
-}
-
-func acmegetsnarf(display draw.Display) Buffer {
-	b := make([]byte, MAXSNARF)
-	n, _, _ := display.ReadSnarf(b)
-	r, _, _ := cvttorunes(b, n)
-	snarf := NewBuffer()
-	snarf.Insert(0, r)
-	return snarf
-}
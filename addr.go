@@ -159,7 +159,8 @@ var pattern *AcmeRegexp
 // Dir indicates the direction of the search: forward or backward.
 // R sets the text position where search begins.
 // Lim sets the text position where search ends for forward search
-// (set range to {-1, -1} for no limit).
+// (set range to {-1, -1} for no limit, in which case the search wraps
+// around at the end of the text, or at the start for backward search).
 // Warnings will be shown to user if showerr is true.
 // It returns the match and whether a match was found.
 func acmeregexp(showerr bool, t Texter, lim Range, r Range, pat string, dir int) (retr Range, found bool) {
@@ -180,12 +181,18 @@ func acmeregexp(showerr bool, t Texter, lim Range, r Range, pat string, dir int)
 	var sel RangeSet
 	if dir == Back {
 		sel = pattern.rxbexecute(t, r.q0, 1)
+		if len(sel) == 0 && lim.q0 < 0 {
+			sel = pattern.rxbexecute(t, t.Nc(), 1)
+		}
 	} else {
 		q := -1
 		if lim.q0 >= 0 {
 			q = lim.q1
 		}
 		sels := pattern.rxexecute(t, nil, r.q1, q, 1)
+		if len(sels) == 0 && lim.q0 < 0 {
+			sels = pattern.rxexecute(t, nil, 0, q, 1)
+		}
 		if len(sels) > 0 {
 			sel = sels[0]
 		} else {
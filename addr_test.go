@@ -86,6 +86,9 @@ func TestAcmeregexp(t *testing.T) {
 		{"NoMatch", "xyz", Fore, Range{-1, -1}, false, "no match for regexp\n"},
 		{"InvalidPat", "(abcd", Fore, Range{0, 0}, false, ""},
 		{"Backwards", "abcd", Back, Range{0, 4}, true, ""},
+		{"IgnoreCase", "(?i)ABCD", Fore, Range{0, 4}, true, ""},
+		{"IgnoreCaseUnicode", "(?i)ΑΒΞΔ", Fore, Range{5, 9}, true, ""},
+		{"Word", `\bab\b`, Fore, Range{-1, -1}, false, "no match for regexp\n"},
 	}
 
 	for _, tc := range tt {
@@ -124,3 +127,40 @@ func TestAcmeregexp(t *testing.T) {
 		})
 	}
 }
+
+func TestAcmeregexpWrap(t *testing.T) {
+	pattern = nil
+	defer func() { pattern = nil }()
+
+	text := &Text{
+		file: &File{
+			b: Buffer([]rune("one two one two\n")),
+		},
+	}
+	nolim := Range{-1, -1}
+	for _, tc := range []struct {
+		name  string
+		lim   Range
+		r     Range
+		dir   int
+		found bool
+		want  Range
+	}{
+		{"Fore", nolim, Range{0, 0}, Fore, true, Range{0, 3}},
+		{"ForeNext", nolim, Range{0, 3}, Fore, true, Range{8, 11}},
+		{"ForeWrap", nolim, Range{8, 11}, Fore, true, Range{0, 3}},
+		{"ForeLimit", Range{0, 16}, Range{8, 11}, Fore, false, Range{-1, -1}},
+		{"Back", nolim, Range{16, 16}, Back, true, Range{8, 11}},
+		{"BackWrap", nolim, Range{0, 3}, Back, true, Range{8, 11}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, found := acmeregexp(false, text, tc.lim, tc.r, "one", tc.dir)
+			if found != tc.found {
+				t.Errorf("found=%v; want %v", found, tc.found)
+			}
+			if r != tc.want {
+				t.Errorf("range=%v; want %v", r, tc.want)
+			}
+		})
+	}
+}
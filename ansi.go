@@ -0,0 +1,126 @@
+package main
+
+import "github.com/fhs/edward/internal/runes"
+
+// ansiState is the state of the escape sequence parser in ansiFilter.
+type ansiState int
+
+const (
+	ansiText   ansiState = iota // ordinary text
+	ansiEsc                     // after ESC
+	ansiCSI                     // inside a control sequence: ESC [ ...
+	ansiOSC                     // inside an operating system command: ESC ] ...
+	ansiOSCEsc                  // after ESC inside an operating system command
+)
+
+// ansiFilter interprets ANSI terminal escape sequences in program
+// output written to a window. The frame has no way to show colour or
+// other attributes, so SGR sequences, along with cursor movement and
+// other control sequences, are removed. Carriage returns are kept only
+// where they are not part of a CRLF line ending; CrInsert interprets
+// them when the text is inserted.
+//
+// A sequence may be split across writes, so the filter keeps its
+// parse state between calls to Filter.
+type ansiFilter struct {
+	state ansiState
+	cr    bool // a carriage return is waiting to see if '\n' follows
+}
+
+// Filter returns r with escape sequences removed and CRLF line endings
+// replaced by '\n'.
+func (f *ansiFilter) Filter(r []rune) []rune {
+	out := make([]rune, 0, len(r))
+	for _, c := range r {
+		switch f.state {
+		case ansiText:
+			if f.cr && c != '\r' {
+				f.cr = false
+				if c != '\n' {
+					out = append(out, '\r')
+				}
+			}
+			switch c {
+			case 0x1B:
+				f.state = ansiEsc
+			case '\r':
+				f.cr = true
+			default:
+				out = append(out, c)
+			}
+		case ansiEsc:
+			switch {
+			case c == '[':
+				f.state = ansiCSI
+			case c == ']':
+				f.state = ansiOSC
+			case c >= 0x20 && c <= 0x2F:
+				// intermediate byte, as in ESC ( B; the sequence continues.
+			default:
+				f.state = ansiText
+			}
+		case ansiCSI:
+			// Parameter and intermediate bytes are in 0x20-0x3F;
+			// the final byte ends the sequence.
+			if c >= 0x40 && c <= 0x7E {
+				f.state = ansiText
+			}
+		case ansiOSC:
+			switch c {
+			case 0x07:
+				f.state = ansiText
+			case 0x1B:
+				f.state = ansiOSCEsc
+			}
+		case ansiOSCEsc:
+			if c == '\\' {
+				f.state = ansiText
+			} else {
+				f.state = ansiOSC
+			}
+		}
+	}
+	return out
+}
+
+// CrInsert is like BsInsert, but each carriage return ('\r') in r erases
+// the line it ends, so that the text following it replaces the line as it
+// would on a terminal. This keeps progress meters that rewrite a single
+// line from filling the window. It returns the lowest position changed
+// and the number of runes from there to the end of the inserted text.
+func (t *Text) CrInsert(q0 int, r []rune, tofile bool) (q, nr int) {
+	q = q0
+	for {
+		i := runes.IndexRune(r, '\r')
+		if i < 0 {
+			break
+		}
+		q1, n := t.BsInsert(q0, r[:i], tofile)
+		q = min(q, q1)
+		q0 = q1 + n
+		p := q0
+		for p > 0 && t.ReadC(p-1) != '\n' {
+			p--
+		}
+		if p < q0 {
+			t.Delete(p, q0, tofile)
+			q0 = p
+			q = min(q, p)
+		}
+		r = r[i+1:]
+	}
+	q1, n := t.BsInsert(q0, r, tofile)
+	q = min(q, q1)
+	return q, q1 + n - q
+}
+
+// insertOutput inserts program output r into t at q0. If the window
+// interprets escape sequences (see the ansi ctl message), r is passed
+// through the window's filter and inserted with CrInsert; otherwise it
+// is inserted with BsInsert.
+func (w *Window) insertOutput(t *Text, q0 int, r []rune) (q, nr int) {
+	if w.ansi == nil {
+		return t.BsInsert(q0, r, true)
+	}
+	return t.CrInsert(q0, w.ansi.Filter(r), true)
+}
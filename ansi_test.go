@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnsiFilter(t *testing.T) {
+	tt := []struct {
+		name string
+		in   []string // successive writes
+		out  string
+	}{
+		{"Plain", []string{"hello\n"}, "hello\n"},
+		{"SGR", []string{"\x1b[1;31merror\x1b[0m: x\n"}, "error: x\n"},
+		{"SplitSGR", []string{"a\x1b[3", "2mb\x1b", "[0mc"}, "abc"},
+		{"CursorMovement", []string{"a\x1b[2Kb\x1b[10;20Hc"}, "abc"},
+		{"Charset", []string{"a\x1b(Bb"}, "ab"},
+		{"TwoCharEscape", []string{"a\x1b7b\x1b8c"}, "abc"},
+		{"OSCBel", []string{"\x1b]0;title\x07text"}, "text"},
+		{"OSCST", []string{"\x1b]0;ti", "tle\x1b\\text"}, "text"},
+		{"CRLF", []string{"a\r\nb\r\n"}, "a\nb\n"},
+		{"SplitCRLF", []string{"a\r", "\nb"}, "a\nb"},
+		{"CR", []string{"10%\r20%\r", "done\n"}, "10%\r20%\rdone\n"},
+		{"DoubleCR", []string{"a\r\r\nb"}, "a\nb"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var f ansiFilter
+			var got []rune
+			for _, s := range tc.in {
+				got = append(got, f.Filter([]rune(s))...)
+			}
+			if string(got) != tc.out {
+				t.Errorf("got %q; want %q", string(got), tc.out)
+			}
+		})
+	}
+}
+
+func TestTextCrInsert(t *testing.T) {
+	tt := []struct {
+		name   string
+		q0, q  int    // Input and returned position
+		buf    string // Initial text buffer
+		in     string // Inserted text
+		outbuf string // Modified text buffer
+		nr     int    // Returned number of runes
+	}{
+		{"NoCR", 4, 4, "abc\n", "xyz", "abc\nxyz", 3},
+		{"CR", 4, 4, "abc\n", "10%\r20%", "abc\n20%", 3},
+		{"CRErasesPartialLine", 6, 4, "abc\n10", "%\r20%", "abc\n20%", 3},
+		{"CRAtEnd", 4, 4, "abc\n", "10%\r", "abc\n", 0},
+		{"CRFirstLine", 0, 0, "", "a\rb\rc", "c", 1},
+		{"CRWithBS", 4, 4, "abc\n", "xy\bz\rq", "abc\nq", 1},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			text := &Text{
+				what: Body,
+				file: &File{
+					b: Buffer(tc.buf),
+				},
+			}
+			q, nr := text.CrInsert(tc.q0, []rune(tc.in), true)
+			if nr != tc.nr {
+				t.Errorf("nr = %v; want %v", nr, tc.nr)
+			}
+			if q != tc.q {
+				t.Errorf("q = %v; want %v", q, tc.q)
+			}
+			if got, want := string(text.file.b), tc.outbuf; !cmp.Equal(got, want) {
+				t.Errorf("text.file.b = %q; want %q", got, want)
+			}
+		})
+	}
+}
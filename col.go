@@ -41,7 +41,13 @@ func (c *Column) Init() *Column {
 // Add adds a window to the Column.
 // TODO(rjk): what are the args?
 func (c *Column) Add(clone *Window, y int) *Window {
-	display, err := drawDev.NewDisplay(nil, *varfontflag, "edward", *winsize)
+	return c.add(clone, y, *winsize)
+}
+
+// add adds a window to the Column, asking for an OS window of the given
+// size and position (in the format of the -W flag).
+func (c *Column) add(clone *Window, y int, size string) *Window {
+	display, err := newdisplay(size)
 	if err != nil {
 		log.Fatalf("can't open display: %v\n", err)
 	}
@@ -53,6 +59,7 @@ func (c *Column) Add(clone *Window, y int) *Window {
 
 	w := NewWindow()
 	w.col = c
+	w.winsize = size
 	if display != nil {
 		display.ScreenImage().Draw(r, w.textcolors[frame.ColBack], nil, image.Point{})
 	}
@@ -75,6 +82,15 @@ func (c *Column) Add(clone *Window, y int) *Window {
 	return w
 }
 
+// newdisplay returns a display for a new window: a new OS window, or one
+// not connected to any window system when running headless.
+func newdisplay(size string) (draw.Display, error) {
+	if *headlessflag {
+		return draw.NewHeadlessDisplay(), nil
+	}
+	return drawDev.NewDisplay(nil, *varfontflag, label, size)
+}
+
 func (c *Column) Close(w *Window, dofree bool) {
 	// w is locked
 	var i int
@@ -89,6 +105,11 @@ Found:
 	w.body.col = nil
 	w.col = nil
 	if dofree {
+		stopsearch(w)
+		if !w.body.file.HasMultipleTexts() {
+			delete(findjobs, w.body.file)
+			delete(replacejobs, w.body.file)
+		}
 		w.Delete()
 		w.Close()
 	}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fhs/edward/internal/complete"
+	"github.com/fhs/edward/internal/draw"
+	"github.com/fhs/edward/internal/frame"
+)
+
+// A Completion is a proposed replacement for the text before the cursor.
+type Completion struct {
+	Q0   int    // start of the text replaced, which ends at the cursor
+	Text string // text replacing it
+}
+
+// A Completer is a source of completions of the text before position q of
+// t. It is called with the window locked, so it must be quick.
+type Completer interface {
+	Complete(t *Text, q int) []Completion
+}
+
+// completers holds the sources of completions, whose completions are
+// listed in this order. Completions printed by the command configured for
+// the file, such as a client of a language server, are added to the list
+// when the command finishes. The command can read the window through the
+// file server, as $winid is set.
+var completers = []Completer{
+	wordCompleter{},
+	fileCompleter{},
+}
+
+const (
+	completelines   = 10               // most completions shown at once
+	completewords   = 100              // most words offered
+	completetimeout = 10 * time.Second // longest a completion command runs
+)
+
+// A completelist is a list of completions of the text before the cursor
+// in a body or tag, shown below the cursor over the body. Up and down, or
+// ^P and ^N, select a completion, and tab or newline replaces the text
+// with it. Typing more of the word narrows the list, and any other key
+// or a change of the selection removes the list.
+type completelist struct {
+	t       *Text
+	q       int             // the cursor
+	all     []Completion    // completions found
+	items   []Completion    // those still matching the text before the cursor
+	sel     int             // selected item
+	top     int             // first item shown
+	pending bool            // waiting for the completion command
+	r       image.Rectangle // where the list is drawn, if it is
+}
+
+// startcomplete completes the text before the cursor in t, listing the
+// completions to choose from if there is more than one. A single
+// completion replaces the text straight away.
+func (t *Text) startcomplete() {
+	if t.readonly() {
+		return
+	}
+	t.TypeCommit()
+	q := t.q0
+	if q < t.Nc() && t.file.ReadC(q) > ' ' { // must be at end of word
+		return
+	}
+	var items []Completion
+	seen := make(map[Completion]bool)
+	for _, c := range completers {
+		for _, it := range c.Complete(t, q) {
+			if !seen[it] && it.Text != string(t.readrunes(it.Q0, q)) {
+				seen[it] = true
+				items = append(items, it)
+			}
+		}
+	}
+	cmd, dir := "", ""
+	if t.w != nil && t.what == Body {
+		cmd = cfg.CompleteFor(t.file.name)
+		dir = t.AbsDirName("")
+		if isremote(dir) {
+			cmd = ""
+		}
+	}
+	if t.w == nil || (cmd == "" && len(items) <= 1) {
+		if len(items) == 1 {
+			t.insertcompletion(items[0], q)
+		}
+		return
+	}
+	w := t.w
+	w.endcomplete()
+	l := &completelist{t: t, q: q, all: items, pending: cmd != ""}
+	w.complete = l
+	l.filter()
+	l.draw()
+	if cmd != "" {
+		env := []string{
+			fmt.Sprintf("winid=%d", w.id),
+			"%=" + t.file.name,
+			"samfile=" + t.file.name,
+		}
+		w.ref.Inc()
+		go w.runcompleter(l, cmd, dir, env, wordstart(t, q))
+	}
+}
+
+// readrunes returns the text of t between q0 and q1.
+func (t *Text) readrunes(q0, q1 int) []rune {
+	r := make([]rune, q1-q0)
+	t.file.b.Read(q0, r)
+	return r
+}
+
+// wordstart returns the start of the word ending at q in t.
+func wordstart(t *Text, q int) int {
+	for q > 0 && isalnum(t.file.ReadC(q-1)) {
+		q--
+	}
+	return q
+}
+
+// insertcompletion replaces the text of t from c.Q0 to the cursor q with
+// c, as a change undone in one step.
+func (t *Text) insertcompletion(c Completion, q int) {
+	if t.readonly() {
+		return
+	}
+	seq++
+	t.file.Mark(seq)
+	t.Delete(c.Q0, q, true)
+	r := []rune(c.Text)
+	t.Insert(c.Q0, r, true)
+	t.Show(c.Q0+len(r), c.Q0+len(r), true)
+	if t.w != nil {
+		t.w.Commit(t)
+	}
+	t.iq1 = t.q0
+}
+
+// completekey handles the key r typed into t while a completion list is
+// shown, returning whether r was used. Keys that continue the word are
+// left to be typed, after which the list is narrowed by completetyped.
+func (w *Window) completekey(t *Text, r rune) bool {
+	l := w.complete
+	if l == nil {
+		return false
+	}
+	if t != l.t || t.q0 != t.q1 || t.q0 != l.q {
+		w.endcomplete()
+		return false
+	}
+	switch {
+	case r == draw.KeyUp || r == 0x10: // ^P
+		l.move(-1)
+	case r == draw.KeyDown || r == 0x0E || r == 0x06 || r == draw.KeyInsert: // ^N, ^F
+		l.move(1)
+	case r == '\t' || r == '\n':
+		if len(l.items) == 0 {
+			w.endcomplete()
+			return false
+		}
+		c := l.items[l.sel]
+		w.endcomplete()
+		t.insertcompletion(c, t.q0)
+	case r == 0x1B: // Escape
+		w.endcomplete()
+	case isalnum(r) || r == 0x08: // ^H
+		return false
+	default:
+		w.endcomplete()
+		return false
+	}
+	return true
+}
+
+// completetyped narrows the completion list of w, if there is one, to the
+// completions of the text now before the cursor in t.
+func (w *Window) completetyped(t *Text) {
+	l := w.complete
+	if l == nil {
+		return
+	}
+	if t.q0 == l.q {
+		return // the key was used by completekey
+	}
+	if t != l.t || t.q0 != t.q1 {
+		w.endcomplete()
+		return
+	}
+	t.TypeCommit()
+	l.q = t.q0
+	l.filter()
+	if len(l.items) == 0 && !l.pending {
+		w.endcomplete()
+		return
+	}
+	l.draw()
+}
+
+// endcomplete removes the completion list of w, if there is one.
+func (w *Window) endcomplete() {
+	if l := w.complete; l != nil {
+		w.complete = nil
+		l.erase()
+	}
+}
+
+// runcompleter runs the completion command cmd in dir for the list l, and
+// adds the lines it prints to l as completions of the text from q0.
+func (w *Window) runcompleter(l *completelist, cmd, dir string, env []string, q0 int) {
+	lines := completecommand(cmd, dir, env)
+	w.Lock('F')
+	defer func() {
+		w.Close() // drop the reference taken by startcomplete
+		w.Unlock()
+	}()
+	if w.complete != l || w.col == nil {
+		return
+	}
+	l.pending = false
+	for _, s := range lines {
+		if s = strings.TrimSpace(s); s != "" {
+			l.all = append(l.all, Completion{q0, s})
+		}
+	}
+	l.filter()
+	if len(l.items) == 0 {
+		w.endcomplete()
+		return
+	}
+	l.draw()
+}
+
+// completecommand runs cmd in dir with the extra environment variables
+// env, returning the lines it prints. The command is run by the shell if
+// one is set, and otherwise split into words.
+func completecommand(cmd, dir string, env []string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), completetimeout)
+	defer cancel()
+	var c *exec.Cmd
+	if acmeshell != "" {
+		c = exec.CommandContext(ctx, acmeshell, "-c", cmd)
+	} else {
+		args := strings.Fields(cmd)
+		c = exec.CommandContext(ctx, args[0], args[1:]...)
+	}
+	c.Dir = dir
+	c.Env = append(os.Environ(), env...)
+	out, err := c.Output()
+	if err != nil {
+		warning(nil, "completion command %q failed: %v\n", cmd, err)
+		return nil
+	}
+	return strings.Split(string(out), "\n")
+}
+
+// filter sets the items of l to the completions that extend the text
+// before the cursor, keeping the selection on the same item if it's
+// still there.
+func (l *completelist) filter() {
+	var sel Completion
+	if l.sel < len(l.items) {
+		sel = l.items[l.sel]
+	}
+	l.items = l.items[:0]
+	l.sel, l.top = 0, 0
+	for _, c := range l.all {
+		if c.Q0 > l.q {
+			continue
+		}
+		typed := string(l.t.readrunes(c.Q0, l.q))
+		if c.Text != typed && strings.HasPrefix(c.Text, typed) {
+			if c == sel {
+				l.sel = len(l.items)
+			}
+			l.items = append(l.items, c)
+		}
+	}
+	l.move(0)
+}
+
+// move moves the selection of l by n items, wrapping around at the ends,
+// and scrolls the list to show it.
+func (l *completelist) move(n int) {
+	if len(l.items) == 0 {
+		return
+	}
+	l.sel = (l.sel + n + len(l.items)) % len(l.items)
+	if l.sel < l.top {
+		l.top = l.sel
+	}
+	if l.sel >= l.top+completelines {
+		l.top = l.sel - completelines + 1
+	}
+	if n != 0 {
+		l.draw()
+	}
+}
+
+// rect returns where l is drawn with font: below the cursor over the body
+// of the window, or above it if there's no room. It is empty if the cursor
+// is not shown.
+func (l *completelist) rect(font draw.Font) image.Rectangle {
+	t := l.t
+	body := &t.w.body
+	area := body.fr.Rect()
+	h := font.Height()
+	if l.q < t.org || l.q > t.org+t.fr.GetFrameFillStatus().Nchars {
+		return image.Rectangle{}
+	}
+	pt := t.fr.Ptofchar(l.q - t.org)
+	if t == body {
+		pt.Y += h
+	} else {
+		pt.Y = area.Min.Y
+	}
+	wid := 0
+	for _, c := range l.items {
+		wid = max(wid, font.StringWidth(c.Text))
+	}
+	n := min(len(l.items), completelines)
+	r := image.Rect(pt.X, pt.Y, pt.X+wid+h+2, pt.Y+n*h+2)
+	if r.Max.Y > area.Max.Y && t == body {
+		r = r.Sub(image.Pt(0, r.Dy()+h))
+	}
+	if r.Max.X > area.Max.X {
+		r = r.Sub(image.Pt(r.Max.X-area.Max.X, 0))
+	}
+	return r.Intersect(area)
+}
+
+// draw draws l in the colours of the tag, over the body of the window.
+func (l *completelist) draw() {
+	w := l.t.w
+	if w.display == nil || w.body.fr == nil {
+		return
+	}
+	font := w.fontget(w.body.font)
+	r := l.rect(font)
+	if !l.r.In(r) {
+		l.erase()
+	}
+	if r.Empty() || len(l.items) == 0 {
+		return
+	}
+	screen := w.display.ScreenImage()
+	inner := r.Inset(1)
+	inner.Min.X += font.Height() / 2
+	fr := frame.NewFrame(inner, font, screen, w.tagcolors)
+	screen.Draw(r, w.tagcolors[frame.ColBack], nil, image.Point{})
+	var sb strings.Builder
+	p0, p1 := 0, 0
+	for i := l.top; i < len(l.items) && i < l.top+completelines; i++ {
+		if i > l.top {
+			sb.WriteString("\n")
+		}
+		if i == l.sel {
+			p0 = len([]rune(sb.String()))
+			p1 = p0 + len([]rune(l.items[i].Text))
+		}
+		sb.WriteString(l.items[i].Text)
+	}
+	fr.Insert([]rune(sb.String()), 0)
+	fr.DrawSel(fr.Ptofchar(p0), p0, p1, true)
+	fr.Clear(true)
+	screen.Border(r, 1, w.tagcolors[frame.ColBord], image.Point{})
+	l.r = r
+	w.display.Flush()
+}
+
+// erase redraws the body under l.
+func (l *completelist) erase() {
+	if l.r.Empty() {
+		return
+	}
+	l.r = image.Rectangle{}
+	w := l.t.w
+	t := &w.body
+	t.Resize(t.all, true, false)
+	t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+	w.display.Flush()
+}
+
+// wordCompleter completes the word before the cursor with words from the
+// bodies of the open windows, those nearest the cursor first.
+type wordCompleter struct{}
+
+func (wordCompleter) Complete(t *Text, q int) []Completion {
+	q0 := wordstart(t, q)
+	if q0 == q {
+		return nil
+	}
+	prefix := string(t.readrunes(q0, q))
+	dist := make(map[string]int)
+	scan := func(b []rune, near func(int) int) {
+		for i := 0; i < len(b); {
+			if !isalnum(b[i]) {
+				i++
+				continue
+			}
+			j := i
+			for j < len(b) && isalnum(b[j]) {
+				j++
+			}
+			if word := string(b[i:j]); len(word) > len(prefix) && strings.HasPrefix(word, prefix) {
+				if d, ok := dist[word]; !ok || near(i) < d {
+					dist[word] = near(i)
+				}
+			}
+			i = j
+		}
+	}
+	scan(t.file.b, func(i int) int {
+		if i > q {
+			return i - q
+		}
+		return q - i
+	})
+	for _, w := range row.col.w {
+		if w.body.file != t.file {
+			scan(w.body.file.b, func(int) int { return 1 << 30 })
+		}
+	}
+	words := make([]string, 0, len(dist))
+	for word := range dist {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if di, dj := dist[words[i]], dist[words[j]]; di != dj {
+			return di < dj
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > completewords {
+		words = words[:completewords]
+	}
+	cs := make([]Completion, len(words))
+	for i, word := range words {
+		cs[i] = Completion{q0, word}
+	}
+	return cs
+}
+
+// fileCompleter completes the file name before the cursor with the names
+// of the files in its directory, relative to the directory of the window
+// if the name isn't rooted.
+type fileCompleter struct{}
+
+func (fileCompleter) Complete(t *Text, q int) []Completion {
+	q1 := q - t.FileWidth(q, true)
+	str := string(t.readrunes(q1, q))
+	path := string(t.readrunes(q1-t.FileWidth(q1, false), q1))
+
+	// is path rooted? if not, we need to make it relative to window path
+	dir := path
+	if !filepath.IsAbs(dir) {
+		dir = t.DirName("")
+		if len(dir) == 0 {
+			dir = Ldot
+		}
+		dir = filepath.Clean(filepath.Join(dir, path))
+	}
+	if isremote(dir) {
+		return nil
+	}
+	c, err := complete.Complete(dir, str)
+	if err != nil || c.NMatch == 0 {
+		return nil
+	}
+	cs := make([]Completion, len(c.Filename))
+	for i, name := range c.Filename {
+		cs[i] = Completion{q1, name}
+	}
+	return cs
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// makeCompleteWindow returns the scaffold window "test", in a temporary
+// directory holding the files tiny.go and tidy/, with the cursor at the
+// end of the body. The directory is removed by the returned function.
+func makeCompleteWindow(t *testing.T) (*Window, func()) {
+	dir, err := ioutil.TempDir("", "edward-complete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "tiny.go"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "tidy"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	w := makeSkeletonWindowModel(Range{len(contents), len(contents)}, filepath.Join(dir, "test"))
+	return w, func() { os.RemoveAll(dir) }
+}
+
+func completiontexts(cs []Completion) []string {
+	s := make([]string, len(cs))
+	for i, c := range cs {
+		s[i] = c.Text
+	}
+	return s
+}
+
+func TestCompleters(t *testing.T) {
+	w, cleanup := makeCompleteWindow(t)
+	defer cleanup()
+	w.body.Type('t')
+	w.body.TypeCommit()
+	q := w.body.Nc()
+
+	got := completiontexts(wordCompleter{}.Complete(&w.body, q))
+	want := []string{"try", "to", "text", "there"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("words are %q; want %q", got, want)
+	}
+	got = completiontexts(fileCompleter{}.Complete(&w.body, q))
+	want = []string{"tidy/", "tiny.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files are %q; want %q", got, want)
+	}
+	if cs := (wordCompleter{}).Complete(&w.body, 0); len(cs) != 0 {
+		t.Errorf("words completing nothing are %v; want none", cs)
+	}
+}
+
+func TestCompletekey(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		keys  string
+		body  string // end of the body
+		items []string
+		undo  string // end of the body after Undo
+	}{
+		{"List", "t\x06", "\nt", []string{"try", "to", "text", "there", "tidy/", "tiny.go"}, ""},
+		{"Unique", "tin\x06", "\ntiny.go", nil, "\ntin"},
+		{"Choose", "t\x06\x0e\t", "\nto", nil, "\nt"},
+		{"Wrap", "t\x06\x10\n", "\ntiny.go", nil, "\nt"},
+		{"Narrow", "t\x06r", "\ntr", []string{"try"}, ""},
+		{"NarrowChoose", "t\x06r\t", "\ntry", nil, "\ntr"},
+		{"Escape", "t\x06\x1b", "\nt", nil, ""},
+		{"OtherKey", "t\x06 ", "\nt ", nil, ""},
+		{"NoMatch", "t\x06z", "\ntz", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w, cleanup := makeCompleteWindow(t)
+			defer cleanup()
+			for _, r := range tc.keys {
+				w.Type(&w.body, r)
+			}
+			w.body.TypeCommit()
+			if got := string(w.body.View(0, w.body.Nc())); !strings.HasSuffix(got, tc.body) {
+				t.Errorf("body is %q; want it to end with %q", got, tc.body)
+			}
+			var items []string
+			if w.complete != nil {
+				items = completiontexts(w.complete.items)
+			}
+			if !reflect.DeepEqual(items, tc.items) {
+				t.Errorf("completions listed are %q; want %q", items, tc.items)
+			}
+			if tc.undo != "" {
+				w.Undo(true)
+				if got := string(w.body.View(0, w.body.Nc())); !strings.HasSuffix(got, tc.undo) {
+					t.Errorf("body after Undo is %q; want it to end with %q", got, tc.undo)
+				}
+			}
+		})
+	}
+}
+
+func TestCompletecommand(t *testing.T) {
+	defer func(s string) { acmeshell = s }(acmeshell)
+	acmeshell = ""
+	dir, err := ioutil.TempDir("", "edward-complete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	got := completecommand("printenv winid", dir, []string{"winid=7"})
+	if want := []string{"7", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("output is %q; want %q", got, want)
+	}
+}
@@ -24,6 +24,7 @@ const (
 	Qlabel
 	Qlog
 	Qnew
+	Qsnarf
 	QWaddr
 	QWbody
 	QWctl
@@ -93,6 +94,7 @@ var (
 	acmeshell string
 	wdir      string
 	editing   = Inactive
+	label     = "edward" // written to the label file; ends every window title
 
 	cplumb     chan *plumb.Message
 	cwait      chan ProcessState
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A dirtree is the state of a directory window shown as a tree, one file
+// per line, in which subdirectories are expanded in place, indented by a
+// tab for each level. It outlives Get, which lists the directory again
+// with the same subdirectories expanded.
+type dirtree struct {
+	dir       string          // directory listed
+	expanded  map[string]bool // expanded subdirectories, by path relative to dir
+	gitignore bool            // leave out files ignored by .gitignore files
+}
+
+// Paths relative to the directory of a dirtree are slash-separated, and
+// those of directories end in a slash.
+
+// treex implements the Tree command, which switches the directory window
+// between the usual columns of names and a tree. With -i, the tree leaves
+// out files ignored by .gitignore files and .git directories; with -a, it
+// shows them all. Look on a directory in the tree expands or collapses it,
+// and on a file opens it.
+func treex(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	w := et.w
+	if !w.body.file.IsDir() {
+		warning(nil, "Tree: %s is not a directory\n", w.body.file.name)
+		return
+	}
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		arg, _ = getarg(argt, false, false)
+	}
+	switch arg {
+	case "":
+		if w.tree != nil {
+			w.tree = nil
+		} else {
+			w.tree = &dirtree{}
+		}
+	case "-i", "-a":
+		if w.tree == nil {
+			w.tree = &dirtree{}
+		}
+		w.tree.gitignore = arg == "-i"
+	default:
+		warning(nil, "usage: Tree [-i|-a]\n")
+		return
+	}
+	w.reloaddir()
+}
+
+// reloaddir lists the directory of w again.
+func (w *Window) reloaddir() {
+	t := &w.body
+	t.Delete(0, t.Nc(), true)
+	t.Load(0, t.file.name, true)
+	t.file.Clean()
+	w.SetTag()
+}
+
+// loadTree loads the tree of the directory filename, containing the files
+// names, into the Text.file.
+func (t *Text) loadTree(q0 int, filename string, names []string) (nread int, err error) {
+	tr := t.w.tree
+	if tr.dir != filename {
+		tr.dir = filename
+		tr.expanded = make(map[string]bool)
+	}
+	var ig *ignorer
+	if tr.gitignore && !isremote(filename) {
+		dir := filepath.Clean(filename)
+		ig = parentignorer(dir).load(dir)
+	}
+	var sb strings.Builder
+	tr.list(&sb, "", names, ig)
+	t.file.InsertAt(q0, []rune(sb.String()))
+	t.w.dirnames = nil
+	t.w.widths = nil
+	return t.file.Size() - q0, nil
+}
+
+// list writes the lines of the tree for the files names in the directory
+// dir, relative to the directory of tr, to sb. Files ignored by ig are
+// left out.
+func (tr *dirtree) list(sb *strings.Builder, dir string, names []string, ig *ignorer) {
+	depth := strings.Count(dir, "/")
+	for _, name := range names {
+		name = filepath.ToSlash(name)
+		p := dir + name
+		isdir := strings.HasSuffix(name, "/")
+		if tr.gitignore && (name == ".git/" || ig != nil && ig.ignored(tr.abspath(p), isdir)) {
+			continue
+		}
+		sb.WriteString(strings.Repeat("\t", depth))
+		sb.WriteString(name)
+		sb.WriteString("\n")
+		if !isdir || !tr.expanded[p] {
+			continue
+		}
+		sub, err := readdirnames(tr.abspath(p))
+		if err != nil {
+			warning(nil, "can't read %s: %v\n", tr.abspath(p), err)
+			continue
+		}
+		subig := ig
+		if ig != nil {
+			subig = ig.load(tr.abspath(p))
+		}
+		tr.list(sb, p, sub, subig)
+	}
+}
+
+// abspath returns the name of the file with path p relative to the
+// directory of tr.
+func (tr *dirtree) abspath(p string) string {
+	if isremote(tr.dir) {
+		return tr.dir + p
+	}
+	return filepath.Join(tr.dir, filepath.FromSlash(p))
+}
+
+// readdirnames returns the sorted names of the files in the directory dir,
+// with a trailing slash on the names of directories.
+func readdirnames(dir string) ([]string, error) {
+	if p, host, file, ok := splitremote(dir); ok {
+		_, names, isdir, err := p.Read(host, file)
+		if err != nil {
+			return nil, err
+		}
+		if !isdir {
+			return nil, ErrNotDir
+		}
+		return names, nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return getDirNames(f)
+}
+
+// treepath returns the path relative to the directory of the tree of the
+// file on the line holding q in the body of w, and the number of that
+// line. The path is read from the line and those of the directories
+// holding it above, so it follows edits to the body; it is "" if the
+// lines don't make a tree.
+func (w *Window) treepath(q int) (p string, line int) {
+	t := &w.body
+	q1 := q
+	for q1 < t.Nc() && t.ReadC(q1) != '\n' {
+		q1++
+	}
+	lines := strings.Split(string(t.readrunes(0, q1)), "\n")
+	line = len(lines) - 1
+	depth := func(s string) int {
+		return len(s) - len(strings.TrimLeft(s, "\t"))
+	}
+	d := depth(lines[line])
+	p = lines[line][d:]
+	if p == "" {
+		return "", line
+	}
+	for i := line - 1; i >= 0 && d > 0; i-- {
+		di := depth(lines[i])
+		if di >= d {
+			continue
+		}
+		if di < d-1 || !strings.HasSuffix(lines[i], "/") {
+			return "", line
+		}
+		p = lines[i][di:] + p
+		d = di
+	}
+	if d > 0 {
+		return "", line
+	}
+	return p, line
+}
+
+// treelook handles Look at q0 in the body of w if it shows a tree,
+// returning whether it did. A directory on the line of q0 is expanded or
+// collapsed, and a file is opened.
+func (w *Window) treelook(q0 int) bool {
+	tr := w.tree
+	if tr == nil {
+		return false
+	}
+	t := &w.body
+	p, line := w.treepath(q0)
+	if p == "" {
+		return false
+	}
+	if !strings.HasSuffix(p, "/") {
+		openfile(t, &Expand{name: tr.abspath(p), jump: true})
+		return true
+	}
+	tr.expanded[p] = !tr.expanded[p]
+	w.reloaddir()
+	q := 0
+	for i := 0; i < line; i++ {
+		for q < t.Nc() && t.file.ReadC(q) != '\n' {
+			q++
+		}
+		q++
+	}
+	q = min(q, t.Nc())
+	t.Show(q, q, true)
+	return true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirtree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edward-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, s := range map[string]string{
+		".gitignore":     "*.o\n",
+		"a.go":           "",
+		"a.o":            "",
+		"sub/b.txt":      "",
+		"sub/deep/c.txt": "",
+		".git/config":    "",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := makeSkeletonWindowModel(Range{0, 0}, dir)
+	w.body.what = Body
+	w.body.file.name = dir
+	w.tree = &dirtree{gitignore: true}
+	w.reloaddir()
+
+	body := func() string { return string(w.body.View(0, w.body.Nc())) }
+	line := func(n int) int {
+		q := 0
+		for ; n > 0; q++ {
+			if w.body.ReadC(q) == '\n' {
+				n--
+			}
+		}
+		return q
+	}
+	for _, tc := range []struct {
+		name string
+		look int // line looked at, or -1 to Get
+		want string
+	}{
+		{"Load", -1, ".gitignore\na.go\nsub/\n"},
+		{"Expand", 2, ".gitignore\na.go\nsub/\n\tb.txt\n\tdeep/\n"},
+		{"ExpandDeeper", 4, ".gitignore\na.go\nsub/\n\tb.txt\n\tdeep/\n\t\tc.txt\n"},
+		{"Get", -1, ".gitignore\na.go\nsub/\n\tb.txt\n\tdeep/\n\t\tc.txt\n"},
+		{"Collapse", 2, ".gitignore\na.go\nsub/\n"},
+		{"Restore", 2, ".gitignore\na.go\nsub/\n\tb.txt\n\tdeep/\n\t\tc.txt\n"},
+		{"PastEnd", 6, ".gitignore\na.go\nsub/\n\tb.txt\n\tdeep/\n\t\tc.txt\n"},
+	} {
+		if tc.look < 0 {
+			w.reloaddir()
+		} else {
+			q := line(tc.look)
+			if got, want := w.treelook(q), q < w.body.Nc(); got != want {
+				t.Errorf("%v: treelook on line %v returned %v; want %v", tc.name, tc.look, got, want)
+			}
+		}
+		if got := body(); got != tc.want {
+			t.Errorf("%v: body is %q; want %q", tc.name, got, tc.want)
+		}
+	}
+
+	// Paths follow edits to the body.
+	w.body.Delete(0, line(1), true)
+	w.body.Insert(line(2), []rune("\tnew.txt\n"), true)
+	for _, tc := range []struct {
+		line int
+		want string
+	}{
+		{0, "a.go"},
+		{1, "sub/"},
+		{2, "sub/new.txt"},
+		{4, "sub/deep/"},
+		{5, "sub/deep/c.txt"},
+		{6, ""},
+	} {
+		if got, _ := w.treepath(line(tc.line)); got != tc.want {
+			t.Errorf("path on line %v of edited body is %q; want %q", tc.line, got, tc.want)
+		}
+	}
+	w.body.Insert(0, []rune("\torphan\n"), true)
+	if got, _ := w.treepath(0); got != "" {
+		t.Errorf("path of line without a parent is %q; want \"\"", got)
+	}
+
+	w.tree.gitignore = false
+	w.tree.expanded = map[string]bool{}
+	w.reloaddir()
+	if got, want := body(), ".git/\n.gitignore\na.go\na.o\nsub/\n"; got != want {
+		t.Errorf("body with all files is %q; want %q", got, want)
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
+
+	"github.com/fhs/edward/internal/file"
+	"github.com/fhs/edward/internal/runes"
 )
 
 var (
@@ -59,6 +63,7 @@ func cmdexec(t *Text, cp *Cmd) bool {
 		t = &t.w.body
 		f = t.file
 		f.curtext = t
+		f.loadrest()
 	}
 	if i >= 0 && cmdtab[i].defaddr != aNo {
 		ap := cp.addr
@@ -252,6 +257,11 @@ func e_cmd(t *Text, cp *Cmd) bool {
 	if err != nil {
 		editerror("%v unreadable", name)
 	}
+	enc := file.DetectEncoding(d)
+	if allreplaced && samename {
+		f.encoding = enc
+	}
+	d = enc.Decode(d)
 	runes, _, nulls := cvttorunes(d, len(d))
 	f.elog.Replace(q0, q1, runes)
 
@@ -324,6 +334,11 @@ func move(f *File, addr2 Address) {
 	}
 }
 
+func k_cmd(t *Text, cp *Cmd) bool {
+	t.file.mark = addr.r
+	return true
+}
+
 func m_cmd(t *Text, cp *Cmd) bool {
 	dot := mkaddr(t.file)
 	addr2 := cmdaddress(cp.mtaddr, dot, 0)
@@ -335,10 +350,27 @@ func m_cmd(t *Text, cp *Cmd) bool {
 	return true
 }
 
+func n_cmd(t *Text, cp *Cmd) bool {
+	seen := make(map[*File]bool)
+	row.AllWindows(func(w *Window) {
+		f := w.body.file
+		if !seen[f] {
+			seen[f] = true
+			pfilename(f)
+		}
+	})
+	return true
+}
+
 func p_cmd(t *Text, cp *Cmd) bool {
 	return pdisplay(t.file)
 }
 
+func q_cmd(t *Text, cp *Cmd) bool {
+	xexit(nil, nil, nil, false, false, "")
+	return true
+}
+
 func s_cmd(t *Text, cp *Cmd) bool {
 	n := cp.num
 	op := -1
@@ -371,42 +403,13 @@ func s_cmd(t *Text, cp *Cmd) bool {
 			break
 		}
 	}
-	rbuf := make([]rune, RBUFSIZE)
+	names := are.SubexpNames()
 	for m := range rp {
-		buf := ""
 		sel = rp[m]
-		for i := 0; i < len(cp.text); i++ {
-			c := []rune(cp.text)[i]
-			if c == '\\' && i < len(cp.text)-1 {
-				i++
-				c = []rune(cp.text)[i]
-				if '1' <= c && c <= '9' {
-					j := c - '0'
-					if sel[j].q1-sel[j].q0 > RBUFSIZE {
-						editerror("replacement string too long")
-					}
-					t.file.b.Read(sel[j].q0, rbuf[:sel[j].q1-sel[j].q0])
-					for k := 0; k < sel[j].q1-sel[j].q0; k++ {
-						buf = buf + string(rbuf[k])
-					}
-				} else {
-					buf += string(c)
-				}
-			} else if c != '&' {
-				buf += string(c)
-			} else {
-				if sel[0].q1-sel[0].q0 > RBUFSIZE {
-					editerror("right hand side too long in substitution")
-				}
-				t.file.b.Read(sel[0].q0, rbuf[:sel[0].q1-sel[0].q0])
-				for k := 0; k < sel[0].q1-sel[0].q0; k++ {
-					buf += string(rbuf[k])
-				}
-			}
-		}
-		t.file.elog.Replace(sel[0].q0, sel[0].q1, []rune(buf))
+		buf := substitute(t.View(0, t.Nc()), sel, names, []rune(cp.text))
+		t.file.elog.Replace(sel[0].q0, sel[0].q1, buf)
 		delta -= sel[0].q1 - sel[0].q0
-		delta += len([]rune(buf))
+		delta += len(buf)
 		didsub = true
 		if cp.flag == 0 {
 			break
@@ -420,6 +423,78 @@ func s_cmd(t *Text, cp *Cmd) bool {
 	return true
 }
 
+// substitute returns the replacement text rhs of an s command for the
+// match sel in text. In rhs, & stands for the matched text, \1 to \9 and
+// \{name} for the text matched by a numbered or named group, and \U and
+// \L convert the text following them to upper or lower case until the
+// next \E.
+func substitute(text []rune, sel RangeSet, names []string, rhs []rune) []rune {
+	var (
+		buf  []rune
+		conv func(rune) rune
+	)
+	add := func(r ...rune) {
+		for _, c := range r {
+			if conv != nil {
+				c = conv(c)
+			}
+			buf = append(buf, c)
+		}
+	}
+	group := func(j int) {
+		if j >= len(sel) {
+			editerror("no group %d in regexp", j)
+		}
+		if sel[j].q0 < 0 { // group didn't take part in the match
+			return
+		}
+		add(text[sel[j].q0:sel[j].q1]...)
+	}
+	for i := 0; i < len(rhs); i++ {
+		c := rhs[i]
+		switch {
+		case c == '&':
+			group(0)
+		case c != '\\' || i == len(rhs)-1:
+			add(c)
+		default:
+			i++
+			c = rhs[i]
+			switch {
+			case '1' <= c && c <= '9':
+				group(int(c - '0'))
+			case c == 'U':
+				conv = unicode.ToUpper
+			case c == 'L':
+				conv = unicode.ToLower
+			case c == 'E':
+				conv = nil
+			case c == '{':
+				n := runes.IndexRune(rhs[i:], '}')
+				if n < 0 {
+					editerror("missing } in s command")
+				}
+				name := string(rhs[i+1 : i+n])
+				i += n
+				j := -1
+				for k, nm := range names {
+					if k > 0 && nm == name {
+						j = k
+						break
+					}
+				}
+				if j < 0 {
+					editerror("no group named %q in regexp", name)
+				}
+				group(j)
+			default:
+				add(c)
+			}
+		}
+	}
+	return buf
+}
+
 func u_cmd(t *Text, cp *Cmd) bool {
 	n := cp.num
 	flag := true
@@ -490,8 +565,12 @@ func runpipe(t *Text, cmd rune, cr []rune, state int) {
 		}
 	}
 	s = append([]rune{cmd}, r...)
+	if cmd == '!' {
+		// The output goes to the +Errors window and the text is left alone.
+		s = r
+	}
 
-	dir := t.DirName("") // exec.Cmd.Dir
+	dir := localdir(t.DirName("")) // exec.Cmd.Dir
 	editing = state
 	if t != nil && t.w != nil {
 		t.w.ref.Inc()
@@ -532,6 +611,11 @@ func pipe_cmd(t *Text, cp *Cmd) bool {
 	return true
 }
 
+func plan9_cmd(t *Text, cp *Cmd) bool {
+	runpipe(t, cp.cmdc, []rune(cp.text), Inactive)
+	return true
+}
+
 func nlcount(t *Text, q0, q1 int) (nl, pnr int) {
 	buf := make([]rune, RBUFSIZE)
 	i := 0
@@ -868,6 +952,9 @@ func nextmatch(f *File, r string, p int, sign int) {
 	sel = RangeSet{Range{0, 0}}
 	if sign >= 0 {
 		sels := are.rxexecute(f.curtext, nil, p, -1, 2)
+		if len(sels) == 0 { // wrap around
+			sels = are.rxexecute(f.curtext, nil, 0, -1, 1)
+		}
 		if len(sels) == 0 {
 			editerror("no match for regexp")
 		} else {
@@ -891,6 +978,9 @@ func nextmatch(f *File, r string, p int, sign int) {
 		}
 	} else {
 		sel = are.rxbexecute(f.curtext, p, NRange)
+		if len(sel) == 0 { // wrap around
+			sel = are.rxbexecute(f.curtext, f.Nr(), NRange)
+		}
 		if len(sel) == 0 {
 			editerror("no match for regexp")
 		}
@@ -925,8 +1015,13 @@ func cmdaddress(ap *Addr, a Address, sign int) Address {
 			a.r.q1 = a.r.q0
 
 		case '\'':
-			editerror("can't handle '")
-			//			a.r = f.mark;
+			a.r = f.mark
+			if a.r.q1 > f.Nr() {
+				a.r.q1 = f.Nr()
+			}
+			if a.r.q0 > a.r.q1 {
+				a.r.q0 = a.r.q1
+			}
 
 		case '?':
 			sign = -sign
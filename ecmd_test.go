@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -60,3 +61,67 @@ func TestXCmdPipeMultipleWindows(t *testing.T) {
 	}
 	X_cmd(nil, cmd)
 }
+
+func TestCmdaddressMark(t *testing.T) {
+	f := NewFile("")
+	f.curtext = &Text{file: f}
+	f.InsertAt(0, []rune("hello, world\n"))
+
+	for _, tc := range []struct {
+		mark Range
+		want Range
+	}{
+		{Range{0, 0}, Range{0, 0}},
+		{Range{3, 7}, Range{3, 7}},
+		{Range{3, 100}, Range{3, 13}}, // the file has shrunk since k
+		{Range{50, 100}, Range{13, 13}},
+	} {
+		f.mark = tc.mark
+		a := cmdaddress(&Addr{typ: '\''}, mkaddr(f), 0)
+		if a.r != tc.want {
+			t.Errorf("' address with mark %v is %v; want %v", tc.mark, a.r, tc.want)
+		}
+	}
+}
+
+func TestKCmd(t *testing.T) {
+	w := NewWindow().initHeadless(nil)
+	text := &w.body
+	f := text.file
+	f.InsertAt(0, []rune("hello, world\n"))
+	text.q0, text.q1 = 7, 12
+
+	cmdexec(text, &Cmd{cmdc: 'k'})
+	if want := (Range{7, 12}); f.mark != want {
+		t.Errorf("mark after k is %v; want %v", f.mark, want)
+	}
+	cmdexec(text, &Cmd{cmdc: 'k', addr: &Addr{typ: 'l', num: 1}})
+	if want := (Range{0, 12}); f.mark != want {
+		t.Errorf("mark after 1k is %v; want %v", f.mark, want)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	long := strings.Repeat("x", 3*RBUFSIZE)
+	text := []rune("Hello, " + long + "!")
+	n := len(text)
+
+	for _, tc := range []struct {
+		sel   RangeSet
+		names []string
+		rhs   string
+		want  string
+	}{
+		{RangeSet{{0, 5}}, nil, "&&", "HelloHello"},
+		{RangeSet{{0, 5}}, nil, `\&\\`, `&\`},
+		{RangeSet{{0, 5}}, nil, `\U&\E-\L&`, "HELLO-hello"},
+		{RangeSet{{0, 5}, {0, 1}, {-1, -1}}, nil, `[\1][\2]`, "[H][]"},
+		{RangeSet{{0, 5}, {1, 5}}, []string{"", "rest"}, `\{rest}`, "ello"},
+		{RangeSet{{0, n}, {7, n - 1}}, nil, `<\1>`, "<" + long + ">"},
+	} {
+		got := string(substitute(text, tc.sel, tc.names, []rune(tc.rhs)))
+		if got != tc.want {
+			t.Errorf("substitute of %q is %q; want %q", tc.rhs, got, tc.want)
+		}
+	}
+}
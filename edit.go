@@ -102,8 +102,11 @@ func init() {
 		{'f', false, false, false, 0, aNo, cNo, wordx, f_cmd},
 		{'g', false, true, false, 'p', aDot, cNo, "", nil}, // Assingned to g_cmd in init() to avoid initialization loop
 		{'i', true, false, false, 0, aDot, cNo, "", i_cmd},
+		{'k', false, false, false, 0, aDot, cNo, "", k_cmd},
 		{'m', false, false, true, 0, aDot, cNo, "", m_cmd},
+		{'n', false, false, false, 0, aNo, cNo, "", n_cmd},
 		{'p', false, false, false, 0, aDot, cNo, "", p_cmd},
+		{'q', false, false, false, 0, aNo, cNo, "", q_cmd},
 		{'r', false, false, false, 0, aDot, cNo, wordx, e_cmd},
 		{'s', false, true, false, 0, aDot, cUnsigned, "", s_cmd},
 		{'t', false, false, true, 0, aDot, cNo, "", m_cmd},
@@ -120,12 +123,7 @@ func init() {
 		{'<', false, false, false, 0, aDot, cNo, linex, pipe_cmd},
 		{'|', false, false, false, 0, aDot, cNo, linex, pipe_cmd},
 		{'>', false, false, false, 0, aDot, cNo, linex, pipe_cmd},
-		/* deliberately unimplemented:
-		{'k', false, false, false, 0, aDot, cNo, "", k_cmd},
-		{'n', false, false, false, 0, aNo, cNo, "", n_cmd},
-		{'q', false, false, false, 0, aNo, cNo, "", q_cmd},
 		{'!', false, false, false, 0, aNo, cNo, linex, plan9_cmd},
-		*/
 	}
 }
 
@@ -183,6 +181,10 @@ func allupdate(w *Window) {
 	t := &w.body
 	f := t.file
 
+	if !f.elog.Empty() && f.ReadOnly() {
+		f.elog.Term()
+		warning(nil, "%s is read-only; changes not applied\n", f.name)
+	}
 	if !f.elog.Empty() {
 		owner := t.w.owner
 		if owner == 0 {
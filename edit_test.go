@@ -98,6 +98,29 @@ func TestEdit(t *testing.T) {
 		// { } NB: grouping requires newlines. And sets . the same for each of the commands.
 		{Range{0, 0}, "test", ",x {\n i/@/ \n a/%/\n }", "@This is a%\n@short text%\n@to try addressing%\n", []string{}},
 		// TODO(rjk): { has a number of constraints not being exercised in this test.
+
+		// k '
+		{Range{5, 7}, "test", "k\n/short/\n'c/junk/", "This junk a\nshort text\nto try addressing\n", []string{}},
+		{Range{0, 0}, "test", "'c/junk/", "junkThis is a\nshort text\nto try addressing\n", []string{}},
+
+		// n
+		{Range{0, 0}, "test", "n", "This is a\nshort text\nto try addressing\n", []string{"'+. test\n'+  alt_example_2\n"}},
+
+		// !
+		{Range{0, 4}, "test", "!echo", "This is a\nshort text\nto try addressing\n", []string{}},
+
+		// s with named groups and case conversion
+		{Range{0, len(contents)}, "test", `s/(?P<first>\w+) (?P<second>\w+)/\{second} \{first}/`, "is This a\nshort text\nto try addressing\n", []string{}},
+		{Range{0, len(contents)}, "test", `s/short (text)/\U&\E \L\1/`, "This is a\nSHORT TEXT text\nto try addressing\n", []string{}},
+		{Range{0, len(contents)}, "test", `s/(sh)ort/\U\1\Eort/g`, "This is a\nSHort text\nto try addressing\n", []string{}},
+		{Range{0, len(contents)}, "test", `s/(short)/\{long}/`, contents, []string{"Edit: no group named \"long\" in regexp\n"}},
+		{Range{0, len(contents)}, "test", `s/short/\1/`, contents, []string{"Edit: no group 1 in regexp\n"}},
+
+		// regexp flags and wraparound in addresses
+		{Range{0, 0}, "test", "/(?i)SHORT/c/long/", "This is a\nlong text\nto try addressing\n", []string{}},
+		{Range{20, 20}, "test", "/This/c/That/", "That is a\nshort text\nto try addressing\n", []string{}},
+		{Range{0, 0}, "test", "-/text/c/junk/", "This is a\nshort junk\nto try addressing\n", []string{}},
+		{Range{0, 0}, "test", "/nothing/c/junk/", contents, []string{"Edit: no match for regexp\n"}},
 	}
 
 	buf := make([]rune, 8192)
@@ -461,6 +484,13 @@ func TestParsecmd(t *testing.T) {
 		{[]rune("u\n"), &Cmd{num: 1, cmdc: 'u'}, nil},
 		{[]rune("u5\n"), &Cmd{num: 5, cmdc: 'u'}, nil},
 		{[]rune("u-3\n"), &Cmd{num: -3, cmdc: 'u'}, nil},
+		{[]rune("k\n"), &Cmd{cmdc: 'k'}, nil},
+		{[]rune("/abc/k\n"), &Cmd{addr: &Addr{typ: '/', re: "abc"}, cmdc: 'k'}, nil},
+		{[]rune("n\n"), &Cmd{cmdc: 'n'}, nil},
+		{[]rune("5n\n"), nil, errAddrNotRequired},
+		{[]rune("q\n"), &Cmd{cmdc: 'q'}, nil},
+		{[]rune("!ls -l\n"), &Cmd{cmdc: '!', text: "ls -l"}, nil},
+		{[]rune("3!ls\n"), nil, errAddrNotRequired},
 	}
 	for _, tc := range tt {
 		lastpat = ""
@@ -696,7 +726,7 @@ func mockrun(win *Window, s string, rdir string, newns bool, argaddr string, xar
 
 		ds := fmt.Sprintf("{%#v %#v %#v %#v %#v %#v}", s, rdir, newns, argaddr, xarg, iseditcmd)
 
-		if s[0] != '>' {
+		if win != nil && s[0] != '>' {
 			row.lk.Lock()
 			win.Lock('M')
 			edittext(win, 4, []rune(ds))
@@ -707,3 +737,32 @@ func mockrun(win *Window, s string, rdir string, newns bool, argaddr string, xar
 		cedit <- 0
 	}()
 }
+
+// BenchmarkEditX measures an Edit x command looking for matches in about
+// 100 MB of text, with one every megabyte or so. The matches are printed
+// rather than changed, which would be slowed down by moving the text.
+func BenchmarkEditX(b *testing.B) {
+	cedit = make(chan int)
+	w := makeSkeletonWindowModel(Range{0, 0}, "test")
+	line := []rune("\tif err := w.body.Load(0, name, true); err != nil { return err }\n")
+	text := make([]rune, 0, 100<<20+len(line))
+	for n := 0; len(text) < 100<<20; n++ {
+		if n%(1<<14) == 0 {
+			text = append(text, []rune("\tfoo(bar)\n")...)
+		}
+		text = append(text, line...)
+	}
+	w.body.file.InsertAt(w.body.Nc(), text)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		row.lk.Lock()
+		w.Lock('M')
+		editcmd(&w.body, []rune(",x/foo\\(.*\\)/"))
+		w.Unlock()
+		row.lk.Unlock()
+		warningsMu.Lock()
+		warnings = []*Warning{}
+		warningsMu.Unlock()
+	}
+}
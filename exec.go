@@ -38,7 +38,9 @@ func init() {
 		{"Delete", del, false, true, true /*unused*/},
 		{"Dump", dump, false, true, true /*unused*/},
 		{"Edit", edit, false, true /*unused*/, true /*unused*/},
+		{"Encoding", encodingx, false, true /*unused*/, true /*unused*/},
 		{"Exit", xexit, false, true /*unused*/, true /*unused*/},
+		{"Find", findx, false, true /*unused*/, true /*unused*/},
 		{"Font", fontx, false, true /*unused*/, true /*unused*/},
 		{"Get", get, false, true, true /*unused*/},
 		{"ID", id, false, true /*unused*/, true /*unused*/},
@@ -53,10 +55,16 @@ func init() {
 		{"Put", put, false, true /*unused*/, true /*unused*/},
 		{"Putall", putall, false, true /*unused*/, true /*unused*/},
 		{"Redo", undo, false, false, true /*unused*/},
+		{"Reload", reload, false, true /*unused*/, true /*unused*/},
+		{"Replace", replacex, false, true /*unused*/, true /*unused*/},
+		{"Search", searchx, false, true /*unused*/, true /*unused*/},
 		{"Send", sendx, true, true /*unused*/, true /*unused*/},
+		{"Session", sessionx, false, true /*unused*/, true /*unused*/},
 		{"Snarf", cut, false, true, false},
+		{"Snarfs", snarfsx, false, true /*unused*/, true /*unused*/},
 		{"Tab", tab, false, true /*unused*/, true /*unused*/},
 		{"Tabexpand", expandtab, false, true /*unused*/, true /*unused*/},
+		{"Tree", treex, false, true /*unused*/, true /*unused*/},
 		{"Undo", undo, false, true, true /*unused*/},
 		{"Zerox", zeroxx, false, true /*unused*/, true /*unused*/},
 	}
@@ -73,7 +81,7 @@ func lookup(r string) *Exectab {
 			return &e
 		}
 	}
-	return nil
+	return lookupscript(words[0])
 }
 
 func isexecc(c rune) bool {
@@ -223,7 +231,7 @@ func execute(t *Text, aq0 int, aq1 int, external bool, argt *Text) {
 	}
 
 	b := r
-	dir := t.DirName("") // exec.Cmd.Dir
+	dir := localdir(t.DirName("")) // exec.Cmd.Dir
 	a, aa := getarg(argt, true, true)
 	if t.w != nil {
 		t.w.ref.Inc()
@@ -247,6 +255,9 @@ func edit(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
 
 func xexit(*Text, *Text, *Text, bool, bool, string) {
 	if row.Clean() {
+		if session != "" {
+			savesession(session)
+		}
 		close(cexit)
 		//	threadexits(nil);
 	}
@@ -313,7 +324,7 @@ func cut(et *Text, t *Text, _ *Text, dosnarf bool, docut bool, _ string) {
 		}
 		acmeputsnarf(t.w.display, snarfbuf)
 	}
-	if docut {
+	if docut && !t.readonly() {
 		t.Delete(t.q0, t.q1, true)
 		t.SetSelect(t.q0, t.q0)
 		if t.w != nil {
@@ -339,7 +350,7 @@ func paste(et *Text, t *Text, _ *Text, selectall bool, tobody bool, _ string) {
 		t = &et.w.body
 		t.file.Mark(seq) // seq has been incremented by execute
 	}
-	if t == nil {
+	if t == nil || t.readonly() {
 		return
 	}
 
@@ -426,12 +437,17 @@ func get(et *Text, _ *Text, argt *Text, flag1 bool, _ bool, arg string) {
 		warning(nil, "no file name\n")
 		return
 	}
-	newNameIsdir, _ := isDir(name)
+	newNameIsdir := false
+	if !isremote(name) {
+		newNameIsdir, _ = isDir(name)
+	}
 	if t.file.HasMultipleTexts() && newNameIsdir {
 		warning(nil, "%s is a directory; can't read with multiple windows on it\n", name)
 		return
 	}
 
+	// Get can be undone, so the whole of a file being loaded is needed.
+	t.file.loadrest()
 	t.Delete(0, t.file.Nr(), true)
 	samename := name == t.file.name
 	t.Load(0, name, samename)
@@ -464,7 +480,7 @@ func xkill(_, _ *Text, argt *Text, _, _ bool, args string) {
 
 func local(et, _, argt *Text, _, _ bool, arg string) {
 	a, aa := getarg(argt, true, true)
-	dir := et.DirName("") // exec.Cmd.Dir
+	dir := localdir(et.DirName("")) // exec.Cmd.Dir
 	run(nil, arg, dir, false, aa, a, false)
 }
 
@@ -472,6 +488,13 @@ func local(et, _, argt *Text, _, _ bool, arg string) {
 //
 // TODO(flux): Write this in terms of the various cases.
 func putfile(f *File, q0 int, q1 int, name string) error {
+	f.loadrest()
+	if f.misencoded {
+		return warnError(nil, "%s not written; part of %s isn't %v and was changed when loaded", name, f.name, f.encoding)
+	}
+	if p, host, file, ok := splitremote(name); ok {
+		return putremote(f, q0, q1, name, p, host, file)
+	}
 	w := f.curtext.w
 	d, err := os.Stat(name)
 
@@ -495,6 +518,11 @@ func putfile(f *File, q0 int, q1 int, name string) error {
 		}
 	}
 
+	b, err := f.Encoded(q0, q1)
+	if err != nil {
+		return warnError(nil, "%s not written; %v", name, err)
+	}
+
 	fd, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
 	if err != nil {
 		return warnError(nil, "can't create file %s: %v", name, err)
@@ -509,7 +537,7 @@ func putfile(f *File, q0 int, q1 int, name string) error {
 		return warnError(nil, "%s not written; file is append only", name)
 	}
 
-	_, err = io.Copy(io.MultiWriter(h, fd), f.b.Reader(q0, q1))
+	_, err = io.MultiWriter(h, fd).Write(b)
 	if err != nil {
 		return warnError(nil, "can't write file %s: %v", name, err)
 	}
@@ -527,6 +555,7 @@ func putfile(f *File, q0 int, q1 int, name string) error {
 				d = d1
 			}
 			f.info = d
+			f.SetReadOnly(false)
 			f.hash.Set(h.Sum(nil))
 			f.Clean()
 		}
@@ -535,6 +564,30 @@ func putfile(f *File, q0 int, q1 int, name string) error {
 	return nil
 }
 
+// putremote writes File f to the file name handled by provider p.
+// Unlike local files, there is no check that the remote file hasn't
+// changed since it was read.
+func putremote(f *File, q0, q1 int, name string, p Provider, host, file string) error {
+	b, err := f.Encoded(q0, q1)
+	if err != nil {
+		return warnError(nil, "%s not written; %v", name, err)
+	}
+	h := sha1.Sum(b)
+	if err := p.Write(host, file, b); err != nil {
+		return warnError(nil, "can't write file %s: %v", name, err)
+	}
+	if name == f.name {
+		if q0 != 0 || q1 != f.Size() {
+			f.Modded()
+		} else {
+			f.hash.Set(h[:])
+			f.Clean()
+		}
+	}
+	f.curtext.w.SetTag()
+	return nil
+}
+
 func put(et *Text, _0 *Text, argt *Text, _1 bool, _2 bool, arg string) {
 	if et == nil || et.w == nil || et.w.body.file.IsDir() {
 		return
@@ -581,6 +634,9 @@ func undo(et *Text, _ *Text, _ *Text, flag1, _ bool, _ string) {
 	if et == nil || et.w == nil {
 		return
 	}
+	if et.w.body.readonly() {
+		return
+	}
 	seq := seqof(et.w, flag1)
 	if seq == 0 {
 		// nothing to undo
@@ -594,7 +650,7 @@ func undo(et *Text, _ *Text, _ *Text, flag1, _ bool, _ string) {
 		if w == et.w {
 			continue
 		}
-		if seqof(w, flag1) == seq {
+		if seqof(w, flag1) == seq && !w.body.file.ReadOnly() {
 			w.Undo(flag1)
 		}
 	}
@@ -626,6 +682,9 @@ func sendx(et, _, _ *Text, _, _ bool, _ string) {
 		return
 	}
 	t := &et.w.body
+	if t.readonly() {
+		return
+	}
 	if t.q0 != t.q1 {
 		cut(t, t, nil, true, false, "")
 	}
@@ -639,11 +698,24 @@ func sendx(et, _, _ *Text, _, _ bool, _ string) {
 	t.Show(t.q1, t.q1, true)
 }
 
+// look implements the Look command. Its argument may start with flags
+// selecting the search mode, as described at parselookflags.
 func look(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
 	if et != nil && et.w != nil {
 		t := &et.w.body
+		mode, smart, arg := parselookflags(arg)
+		find := func(r []rune) {
+			if smart {
+				mode.fold = smartcase(r)
+			}
+			search(t, r, mode)
+		}
 		if len(arg) > 0 {
-			search(t, []rune(arg))
+			find([]rune(arg))
+			return
+		}
+		if job := findjobof(t); job != nil && argt == nil {
+			job.open(t)
 			return
 		}
 		r, _ := getarg(argt, false, false)
@@ -653,7 +725,7 @@ func look(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
 			t.file.b.Read(t.q0, rb[:n])
 			r = string(rb) // TODO(flux) Too many gross []rune-string conversions in here
 		}
-		search(t, []rune(r))
+		find([]rune(r))
 	}
 }
 
@@ -705,6 +777,60 @@ func expandtab(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
 	}
 }
 
+// encodingx implements the Encoding command. Without arguments it shows
+// the encoding of the window's file; otherwise it changes the encoding
+// used by Put, as in "Encoding latin1 crlf".
+func encodingx(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	w := et.w
+	r, _ := getarg(argt, false, true)
+	s := strings.TrimSpace(r + " " + arg)
+	if s == "" {
+		warning(nil, "%s: Encoding %v\n", w.body.file.name, w.body.file.Encoding())
+		return
+	}
+	enc, err := w.body.file.Encoding().Parse(s)
+	if err == nil {
+		err = setencoding(w, enc)
+	}
+	if err != nil {
+		warning(nil, "Encoding: %v\n", err)
+	}
+}
+
+// setencoding changes the encoding of the file in w. Switching to or from
+// hex rewrites the body, which shows a hex dump instead of the text, and
+// so is refused if the body is read-only.
+func setencoding(w *Window, enc file.Encoding) error {
+	t := &w.body
+	f := t.file
+	if (f.Encoding().Charset == file.Hex) != (enc.Charset == file.Hex) {
+		if f.ReadOnly() {
+			return ErrReadOnly
+		}
+		b, err := f.Encoded(0, f.Size())
+		if err != nil {
+			return err
+		}
+		b = enc.Decode(b)
+		r, _, _ := cvttorunes(b, len(b))
+		t.Commit()
+		seq++
+		f.Mark(seq)
+		t.Delete(0, t.Nc(), true)
+		t.Insert(0, r, true)
+		t.SetSelect(0, 0)
+		if t.fr != nil {
+			t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+		}
+	}
+	f.SetEncoding(enc)
+	w.SetTag()
+	return nil
+}
+
 func fontx(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
 	if et == nil || et.w == nil {
 		return
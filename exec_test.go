@@ -8,6 +8,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/fhs/edward/internal/edwoodtest"
+	"github.com/fhs/edward/internal/file"
 )
 
 func acmeTestingMain() {
@@ -92,6 +95,83 @@ func TestRunproc(t *testing.T) {
 	}
 }
 
+func TestRunRemoteWindow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no true command on windows")
+	}
+	dir, err := ioutil.TempDir("", "edward-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldwdir, oldshell := wdir, acmeshell
+	wdir, acmeshell = dir, ""
+	ccommand = make(chan *Command, 1)
+	cwait = make(chan ProcessState, 1)
+	defer func() {
+		wdir, acmeshell = oldwdir, oldshell
+		ccommand = nil
+		cwait = nil
+	}()
+
+	w := makeSkeletonWindowModel(Range{0, 0}, "ssh://host/dir/file")
+	local(&w.body, nil, nil, false, false, "true")
+	select {
+	case c := <-ccommand:
+		if c.proc == nil {
+			t.Fatalf("command from remote window didn't start")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command from remote window didn't start")
+	}
+	<-cwait
+
+	m, err := look3Message(&w.body, 0, 0)
+	if err != nil {
+		t.Fatalf("look3Message failed: %v", err)
+	}
+	if m.Dir != dir {
+		t.Errorf("plumb message directory is %q; want %q", m.Dir, dir)
+	}
+}
+
+func TestPutfileEncoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edwood.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const want = "caf\xe9\r\nbar\r\n"
+	filename := filepath.Join(dir, "latin1.txt")
+	if err := ioutil.WriteFile(filename, []byte(want), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	text := emptyText()
+	text.file.name = filename
+	text.file.curtext = text
+	if _, err := text.Load(0, filename, true); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got, want := string(text.file.b), "café\nbar\n"; got != want {
+		t.Errorf("loaded text %q; expected %q", got, want)
+	}
+	if err := putfile(text.file, 0, text.file.Size(), filename); err != nil {
+		t.Fatalf("putfile failed: %v", err)
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != want {
+		t.Errorf("file content is %q; expected %q", b, want)
+	}
+
+	text.file.InsertAt(0, []rune("世界"))
+	if err := putfile(text.file, 0, text.file.Size(), filename); err == nil {
+		t.Errorf("putfile of text not in latin1 succeeded")
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != want {
+		t.Errorf("failed putfile changed file content to %q", b)
+	}
+}
+
 func TestPutfile(t *testing.T) {
 	dir, err := ioutil.TempDir("", "edwood.test")
 	if err != nil {
@@ -184,3 +264,71 @@ func TestExpandtabToggle(t *testing.T) {
 		t.Errorf("tabexpand is set to %v; expected %v", te, want)
 	}
 }
+
+func TestSetencodingHex(t *testing.T) {
+	configureGlobals()
+	display := edwoodtest.NewDisplay()
+	w := NewWindow().initHeadless(nil)
+	w.display = display
+	w.col = new(Column)
+	w.body.display = display
+	w.body.fr = &MockFrame{}
+	w.tag.display = display
+	w.tag.fr = &MockFrame{}
+	w.body.file.b = Buffer("hi\n")
+
+	const dump = "00000000: 68 69 0a                                          |hi.|\n"
+	if err := setencoding(w, file.Encoding{Charset: file.Hex}); err != nil {
+		t.Fatalf("setencoding failed: %v", err)
+	}
+	if got := string(w.body.file.b); got != dump {
+		t.Errorf("body is %q; expected %q", got, dump)
+	}
+	if err := setencoding(w, file.Encoding{}); err != nil {
+		t.Fatalf("setencoding failed: %v", err)
+	}
+	if got, want := string(w.body.file.b), "hi\n"; got != want {
+		t.Errorf("body is %q; expected %q", got, want)
+	}
+
+	w.body.file.SetReadOnly(true)
+	if err := setencoding(w, file.Encoding{Charset: file.Hex}); err != ErrReadOnly {
+		t.Errorf("setencoding of read-only body returned %v; expected %v", err, ErrReadOnly)
+	}
+	if got, want := string(w.body.file.b), "hi\n"; got != want {
+		t.Errorf("read-only body is %q; expected %q", got, want)
+	}
+}
+
+func TestReadOnlyEdits(t *testing.T) {
+	defer func() { warnings = nil }()
+
+	for _, tc := range []struct {
+		name string
+		edit func(w *Window)
+	}{
+		{"Cut", func(w *Window) { cut(&w.tag, &w.body, nil, true, true, "") }},
+		{"ChordCut", func(w *Window) { cut(&w.body, &w.body, nil, true, true, "") }},
+		{"Paste", func(w *Window) { paste(&w.tag, nil, nil, true, true, "") }},
+		{"ChordPaste", func(w *Window) { paste(&w.body, &w.body, nil, true, false, "") }},
+		{"Send", func(w *Window) { sendx(&w.tag, nil, nil, false, false, "") }},
+		{"Undo", func(w *Window) { undo(&w.tag, nil, nil, true, false, "") }},
+		{"Complete", func(w *Window) { w.body.insertcompletion(Completion{0, "X"}, 1) }},
+		{"Encoding", func(w *Window) { encodingx(&w.tag, nil, nil, false, false, "hex") }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := makeSkeletonWindowModel(Range{0, 4}, "test")
+			w.body.display.WriteSnarf([]byte("snarfed"))
+			seq++
+			w.body.file.Mark(seq)
+			w.body.Insert(0, []rune("T"), true)
+			w.body.file.SetReadOnly(true)
+			want := string(w.body.View(0, w.body.Nc()))
+
+			tc.edit(w)
+			if got := string(w.body.View(0, w.body.Nc())); got != want {
+				t.Errorf("read-only body changed to %q; want %q", got, want)
+			}
+		})
+	}
+}
@@ -62,8 +62,14 @@ type File struct {
 
 	isscratch bool // Used to track if this File should warn on unsaved deletion. [private]
 	isdir     bool // Used to track if this File is populated from a directory list. [private]
+	readonly  bool // Used to refuse edits because the disk file can't be written. [private]
 
-	hash file.Hash // Used to check if the file has changed on disk since loaded.
+	hash       file.Hash     // Used to check if the file has changed on disk since loaded.
+	encoding   file.Encoding // Used to convert the contents to and from the disk file.
+	loading    *lazyload     // Used to load the rest of a large disk file in the background.
+	misencoded bool          // Used to refuse Put when part of the disk file wasn't in encoding.
+
+	mark Range // The ' address, set by the Edit k command. Not moved by later edits.
 
 	// cache holds  that are not yet part of an undo record.
 	cache []rune // [private]
@@ -133,6 +139,41 @@ func (f *File) SetDir(flag bool) {
 	f.isdir = flag
 }
 
+// ReadOnly returns true if edits to the File are refused because its
+// disk file can't be written.
+func (f *File) ReadOnly() bool {
+	return f.readonly
+}
+
+// SetReadOnly updates the setting of the readonly flag.
+func (f *File) SetReadOnly(flag bool) {
+	f.readonly = flag
+}
+
+// Encoding returns the encoding of the disk file.
+func (f *File) Encoding() file.Encoding {
+	return f.encoding
+}
+
+// SetEncoding changes the encoding used when writing the disk file.
+// The File becomes dirty because the disk file no longer matches it.
+func (f *File) SetEncoding(e file.Encoding) {
+	if e != f.encoding {
+		f.encoding = e
+		f.Modded()
+	}
+}
+
+// Encoded returns the contents of the File from q0 to q1 converted to
+// the encoding of its disk file.
+func (f *File) Encoded(q0, q1 int) ([]byte, error) {
+	b, err := ioutil.ReadAll(f.b.Reader(q0, q1))
+	if err != nil {
+		return nil, err
+	}
+	return f.encoding.Encode(b)
+}
+
 // Size returns the complete size of the buffer including both committed
 // and uncommitted runes.
 // NB: naturally forwards to undo.Buffer.Size()
@@ -236,11 +277,14 @@ func (f *File) Load(q0 int, fd io.Reader, sethash bool) (n int, hasNulls bool, e
 	if err != nil {
 		warning(nil, "read error in Buffer.Load")
 	}
-	runes, _, hasNulls := cvttorunes(d, len(d))
-
+	enc := file.DetectEncoding(d)
 	if sethash {
 		f.hash = file.CalcHash(d)
+		f.encoding = enc
+		f.misencoded = false
 	}
+	d = enc.Decode(d)
+	runes, _, hasNulls := cvttorunes(d, len(d))
 
 	// Would appear to require a commit operation.
 	// NB: Runs the observers.
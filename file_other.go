@@ -0,0 +1,11 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import "os"
+
+// writable reports whether the file described by d can be written by us,
+// judging by its permission bits.
+func writable(d os.FileInfo) bool {
+	return d.Mode().Perm()&0222 != 0
+}
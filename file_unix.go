@@ -0,0 +1,40 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// writable reports whether the file described by d can be written by us,
+// judging by its permission bits.
+func writable(d os.FileInfo) bool {
+	st, ok := d.Sys().(*syscall.Stat_t)
+	if !ok {
+		return d.Mode().Perm()&0222 != 0
+	}
+	uid := os.Getuid()
+	switch {
+	case uid == 0:
+		return true
+	case int(st.Uid) == uid:
+		return d.Mode()&0200 != 0
+	case ingroup(int(st.Gid)):
+		return d.Mode()&0020 != 0
+	}
+	return d.Mode()&0002 != 0
+}
+
+func ingroup(gid int) bool {
+	if gid == os.Getgid() {
+		return true
+	}
+	groups, _ := os.Getgroups()
+	for _, g := range groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Name of the window listing the files found by Find, in the project root.
+const findName = "+Find"
+
+// Most files listed in a +Find window.
+const findmax = 1000
+
+// A findjob holds the files that can be listed in a +Find window.
+type findjob struct {
+	root  string
+	files []findfile
+	query string // query the list was last filtered by
+}
+
+// A findfile is a file under the root of a findjob.
+type findfile struct {
+	name  string    // path relative to the root, slash-separated
+	mtime time.Time // modification time
+}
+
+// findjobs holds the jobs of +Find windows, by the File of the window. It
+// is guarded by row.lk.
+var findjobs = make(map[*File]*findjob)
+
+// findjobof returns the job of the +Find window whose body is t, or nil.
+// The job is dropped if the window has been renamed.
+func findjobof(t *Text) *findjob {
+	job, ok := findjobs[t.file]
+	if !ok {
+		return nil
+	}
+	if t.file.name != filepath.Join(job.root, findName) {
+		delete(findjobs, t.file)
+		return nil
+	}
+	return job
+}
+
+// findx implements the Find command. It lists the files under the root of
+// the project holding the window's directory in a +Find window, skipping
+// those ignored by .gitignore files. The list is filtered by the query
+// following the word Find after the bar in the window's tag, matched
+// fuzzily, and is updated as the query is typed. The best matches come
+// first, the most recently changed and open files first among equals.
+// Look opens the file on the selected line. An argument to Find becomes
+// the query; in a +Find window, Find reads the files again.
+func findx(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	query := strings.TrimSpace(arg)
+	if query == "" {
+		query, _ = getarg(argt, false, false)
+	}
+	w := et.w
+	job := findjobof(&w.body)
+	if job == nil {
+		dir := w.body.AbsDirName("")
+		if isremote(dir) {
+			warning(nil, "Find: can't find files in remote directory %v\n", dir)
+			return
+		}
+		root := projectroot(dir)
+		if root == "" {
+			root = dir
+		}
+		name := filepath.Join(root, findName)
+		w = lookfile(name)
+		if w == nil {
+			w = row.col.Add(nil, -1)
+			defer w.HandleInput()
+			w.filemenu = false
+			w.SetName(name)
+			xfidlog(w, "new")
+		}
+		if w.body.file != et.w.body.file {
+			w.Lock('E')
+			defer w.Unlock()
+		}
+		job = findjobof(&w.body)
+		if job == nil {
+			job = &findjob{root: root}
+			findjobs[w.body.file] = job
+		}
+	}
+	w.SetTag()
+	if query != "" {
+		w.setfindquery(query)
+	}
+	root := job.root
+	var files []findfile
+	w.walkwindow(func(stop <-chan struct{}) {
+		files = findfiles(root, stop)
+	}, func() {
+		job.files = files
+		job.query = w.findquery()
+		job.show(&w.body)
+	})
+}
+
+// findfiles returns the files under root that aren't ignored, in no
+// particular order. Closing stop ends the walk early.
+func findfiles(root string, stop <-chan struct{}) []findfile {
+	var (
+		mu    sync.Mutex
+		files []findfile
+	)
+	walktree(root, stop, func(p string) {
+		info, err := os.Stat(p)
+		if err != nil {
+			return
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		files = append(files, findfile{filepath.ToSlash(name), info.ModTime()})
+		mu.Unlock()
+	})
+	return files
+}
+
+// findqueryrange returns the range of the tag of w holding the query of a
+// +Find window: the text after the last word Find following the bar. If
+// there is no such word, the range is empty, at the end of the tag.
+func (w *Window) findqueryrange() (q0, q1 int) {
+	n := w.tag.Nc()
+	r := make([]rune, n)
+	w.tag.file.b.Read(0, r)
+	bar := -1
+	for i, c := range r {
+		if c == '|' {
+			bar = i
+			break
+		}
+	}
+	if bar < 0 {
+		return n, n
+	}
+	for i := n - 4; i > bar; i-- {
+		if string(r[i:i+4]) == "Find" && unicode.IsSpace(r[i-1]) && (i+4 == n || unicode.IsSpace(r[i+4])) {
+			return i + 4, n
+		}
+	}
+	return n, n
+}
+
+// findquery returns the query in the tag of the +Find window w.
+func (w *Window) findquery() string {
+	w.Commit(&w.tag)
+	q0, q1 := w.findqueryrange()
+	r := make([]rune, q1-q0)
+	w.tag.file.b.Read(q0, r)
+	return strings.TrimSpace(string(r))
+}
+
+// setfindquery replaces the query in the tag of the +Find window w,
+// adding the word Find to the tag if it isn't there.
+func (w *Window) setfindquery(query string) {
+	w.Commit(&w.tag)
+	q0, q1 := w.findqueryrange()
+	s := " " + query
+	if q0 == q1 {
+		s = " Find" + s
+		if q0 > 0 && unicode.IsSpace(w.tag.file.ReadC(q0-1)) {
+			s = s[1:]
+		}
+	}
+	w.tag.Delete(q0, q1, true)
+	w.tag.Insert(q0, []rune(s), true)
+	w.tag.SetSelect(w.tag.Nc(), w.tag.Nc())
+}
+
+// findrefresh filters the list of w again if w is a +Find window whose
+// query has changed.
+func (w *Window) findrefresh() {
+	job := findjobof(&w.body)
+	if job == nil {
+		return
+	}
+	if q := w.findquery(); q != job.query {
+		job.query = q
+		job.show(&w.body)
+	}
+}
+
+// show lists the files of job that match its query in t, best first.
+func (job *findjob) show(t *Text) {
+	var sb strings.Builder
+	for _, name := range job.rank() {
+		sb.WriteString(name)
+		sb.WriteString("\n")
+	}
+	t.Delete(0, t.Nc(), true)
+	t.Insert(0, []rune(sb.String()), true)
+	t.file.Clean()
+	t.SetSelect(0, 0)
+	if t.fr != nil {
+		t.SetOrigin(0, true)
+		t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+	}
+}
+
+// rank returns the names of at most findmax files of job matching its
+// query, ordered by score, then with files open in windows and the most
+// recently modified files first.
+func (job *findjob) rank() []string {
+	query := []rune(job.query)
+	fold := smartcase(query)
+	open := make(map[string]bool)
+	for _, w := range row.col.w {
+		open[w.body.file.name] = true
+	}
+	type match struct {
+		name  string
+		score int
+		open  bool
+		mtime time.Time
+	}
+	var ms []match
+	for _, f := range job.files {
+		score, ok := fuzzyscore([]rune(f.name), query, fold)
+		if !ok {
+			continue
+		}
+		ms = append(ms, match{
+			name:  f.name,
+			score: score,
+			open:  open[filepath.Join(job.root, filepath.FromSlash(f.name))],
+			mtime: f.mtime,
+		})
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		a, b := &ms[i], &ms[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.open != b.open:
+			return a.open
+		case !a.mtime.Equal(b.mtime):
+			return a.mtime.After(b.mtime)
+		}
+		return a.name < b.name
+	})
+	if len(ms) > findmax {
+		ms = ms[:findmax]
+	}
+	names := make([]string, len(ms))
+	for i := range ms {
+		names[i] = ms[i].name
+	}
+	return names
+}
+
+// open opens the file listed on the line of t holding its selection.
+func (job *findjob) open(t *Text) {
+	q0 := t.q0
+	for q0 > 0 && t.ReadC(q0-1) != '\n' {
+		q0--
+	}
+	q1 := q0
+	for q1 < t.Nc() && t.ReadC(q1) != '\n' {
+		q1++
+	}
+	r := make([]rune, q1-q0)
+	t.file.b.Read(q0, r)
+	name := strings.TrimSpace(string(r))
+	if name == "" {
+		return
+	}
+	openfile(t, &Expand{name: filepath.Join(job.root, filepath.FromSlash(name)), jump: true})
+}
+
+// Scores of the runes of a fuzzy match.
+const (
+	fuzzymatch       = 16 // a rune of the query
+	fuzzyconsecutive = 16 // following the previous matched rune
+	fuzzyelement     = 16 // at the start of a path element
+	fuzzyword        = 12 // at the start of a word in a path element
+	fuzzybase        = 4  // in the last path element
+	fuzzygapstart    = 8  // skipping runes between matched runes
+	fuzzygap         = 1  // each rune skipped, and each rune after the match
+)
+
+// fuzzyscore reports whether the runes of query appear in order in name,
+// ignoring case if fold is set, and returns the best score of the ways
+// they can be matched. Matched runes score more when they follow each
+// other or start a path element or word, and in the base name. Runes
+// skipped between matched runes cost a little, as do those following
+// the match, so shorter names come first.
+func fuzzyscore(name, query []rune, fold bool) (int, bool) {
+	if len(query) == 0 {
+		return 0, true
+	}
+	eq := func(a, b rune) bool {
+		return a == b || fold && unicode.ToLower(a) == b
+	}
+	// Quick check that there's a match at all.
+	j := 0
+	for _, c := range name {
+		if j < len(query) && eq(c, query[j]) {
+			j++
+		}
+	}
+	if j < len(query) {
+		return 0, false
+	}
+
+	base := 0 // start of the last path element
+	for i, c := range name {
+		if c == '/' {
+			base = i + 1
+		}
+	}
+	const none = -1 << 30
+	// m[j] is the best score with query[j] matched at the rune before
+	// name[i], and h[j] the best with query[:j+1] matched before name[i].
+	m := make([]int, len(query))
+	h := make([]int, len(query))
+	nm := make([]int, len(query))
+	for j := range m {
+		m[j], h[j] = none, none
+	}
+	for i, c := range name {
+		bonus := 0
+		switch {
+		case i == 0 || name[i-1] == '/':
+			bonus = fuzzyelement
+		case strings.ContainsRune("_-. ", name[i-1]),
+			unicode.IsLower(name[i-1]) && unicode.IsUpper(c),
+			!unicode.IsDigit(name[i-1]) && unicode.IsDigit(c):
+			bonus = fuzzyword
+		}
+		if i >= base {
+			bonus += fuzzybase
+		}
+		for j := range query {
+			nm[j] = none
+			if !eq(c, query[j]) {
+				continue
+			}
+			prev, prevm := 0, none
+			if j > 0 {
+				prev, prevm = h[j-1], m[j-1]
+				if prev != none {
+					prev -= fuzzygapstart
+				}
+				if prevm != none {
+					prevm += fuzzyconsecutive
+				}
+			}
+			if prev == none && prevm == none {
+				continue
+			}
+			nm[j] = fuzzymatch + bonus + max(prev, prevm)
+		}
+		for j := range query {
+			m[j] = nm[j]
+			if h[j] != none {
+				h[j] -= fuzzygap
+			}
+			h[j] = max(h[j], m[j])
+		}
+	}
+	return h[len(query)-1], true
+}
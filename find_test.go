@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFuzzyscore(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		names []string // in order of decreasing score
+	}{
+		{"wind", []string{"wind.go", "wind_test.go", "internal/wind/x.go", "wrapindent.go"}},
+		{"ft", []string{"find_test.go", "internal/frame/text.go", "foot.go"}},
+		{"Ab", []string{"Ab", "xAxb", "ab"}}, // ab doesn't match
+		{"rt", []string{"row_test.go", "rowtest.go"}},
+		{"xt", []string{"x/t", "x/at"}},
+	} {
+		last, lastname := 1<<30, ""
+		fold := smartcase([]rune(tc.query))
+		for _, name := range tc.names {
+			score, ok := fuzzyscore([]rune(name), []rune(tc.query), fold)
+			if name == "ab" {
+				if ok {
+					t.Errorf("%q matches %q", tc.query, name)
+				}
+				continue
+			}
+			if !ok {
+				t.Errorf("%q doesn't match %q", tc.query, name)
+				continue
+			}
+			if score >= last {
+				t.Errorf("%q scores %d in %q, not less than %d in %q", tc.query, score, name, last, lastname)
+			}
+			last, lastname = score, name
+		}
+	}
+	if _, ok := fuzzyscore([]rune("abc"), []rune("ca"), true); ok {
+		t.Errorf("query matched out of order")
+	}
+	if score, ok := fuzzyscore([]rune("abc"), nil, true); score != 0 || !ok {
+		t.Errorf("empty query is %d, %v; want 0, true", score, ok)
+	}
+}
+
+func TestFindjobRank(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "/p/+Find")
+	now := time.Now()
+	job := &findjob{
+		root: "/p",
+		files: []findfile{
+			{"a.go", now.Add(-time.Hour)},
+			{"b.go", now},
+			{"test", now.Add(-2 * time.Hour)},
+			{"x/wind.go", now.Add(-3 * time.Hour)},
+		},
+	}
+	w.body.file.name = "/p/test"
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"test", "b.go", "a.go", "x/wind.go"}},
+		{"go", []string{"b.go", "a.go", "x/wind.go"}},
+		{"wgo", []string{"x/wind.go"}},
+		{"z", []string{}},
+	} {
+		job.query = tc.query
+		if got := job.rank(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("files ranked for %q are %q; want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestFindquery(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "/p/+Find Del Snarf | Look Find ")
+	w.body.what = Body
+	w.tag.what = Tag
+	job := &findjob{
+		root: "/p",
+		files: []findfile{
+			{"find.go", time.Time{}},
+			{"find_test.go", time.Time{}},
+			{"wind.go", time.Time{}},
+		},
+	}
+	findjobs[w.body.file] = job
+	defer delete(findjobs, w.body.file)
+
+	body := func() string { return string(w.body.View(0, w.body.Nc())) }
+	n := w.tag.Nc()
+	w.tag.SetSelect(n, n)
+	for _, r := range "ft" {
+		w.Type(&w.tag, r)
+	}
+	if got, want := w.findquery(), "ft"; got != want {
+		t.Errorf("query is %q; want %q", got, want)
+	}
+	if got, want := body(), "find_test.go\n"; got != want {
+		t.Errorf("body is %q; want %q", got, want)
+	}
+	w.Type(&w.tag, '\b')
+	if got, want := body(), "find.go\nfind_test.go\n"; got != want {
+		t.Errorf("body after erasing is %q; want %q", got, want)
+	}
+
+	w.setfindquery("ind")
+	if got, want := string(w.tag.View(0, w.tag.Nc())), "/p/+Find Del Snarf | Look Find ind"; got != want {
+		t.Errorf("tag is %q; want %q", got, want)
+	}
+	w.tag.Delete(w.tag.Nc()-len(" Find ind"), w.tag.Nc(), true)
+	w.setfindquery("w")
+	if got, want := string(w.tag.View(0, w.tag.Nc())), "/p/+Find Del Snarf | Look Find w"; got != want {
+		t.Errorf("tag is %q; want %q", got, want)
+	}
+}
+
+func TestFindjobof(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "/p/+Find Del Snarf | Look")
+	job := &findjob{root: "/p"}
+	findjobs[w.body.file] = job
+	defer delete(findjobs, w.body.file)
+
+	if got := findjobof(&w.body); got != job {
+		t.Errorf("got job %v; want %v", got, job)
+	}
+	w.body.file.SetName("/p/notes")
+	if got := findjobof(&w.body); got != nil {
+		t.Errorf("got job %v after rename; want nil", got)
+	}
+	if _, ok := findjobs[w.body.file]; ok {
+		t.Errorf("job of renamed window not dropped")
+	}
+}
+
+func TestFindfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edward-find")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, s := range map[string]string{
+		".gitignore":  "*.o\n",
+		"a.go":        "",
+		"a.o":         "",
+		"sub/b.txt":   "",
+		".git/config": "",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for _, f := range findfiles(dir, nil) {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), ".gitignore a.go sub/b.txt"; got != want {
+		t.Errorf("files found are %q; want %q", got, want)
+	}
+}
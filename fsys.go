@@ -63,6 +63,7 @@ var dirtab = []*DirTab{
 	{"label", plan9.QTFILE, Qlabel, 0600},
 	{"log", plan9.QTFILE, Qlog, 0400},
 	{"new", plan9.QTDIR, Qnew, 0500 | plan9.DMDIR},
+	{"snarf", plan9.QTFILE, Qsnarf, 0600},
 }
 
 var dirtabw = []*DirTab{
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"9fans.net/go/acme"
 	"9fans.net/go/plan9"
@@ -131,7 +132,8 @@ func startAcme(t *testing.T, args ...string) *Acme {
 	augmentPathEnv()
 
 	acmd := exec.Command(os.Args[0], args...)
-	acmd.Env = append(os.Environ(), "TEST_MAIN=edwood")
+	// Keep the user's configuration, scripts and sessions out of the test.
+	acmd.Env = append(os.Environ(), "TEST_MAIN=edwood", "XDG_CONFIG_HOME="+ns)
 
 	acmd.Stdout = os.Stdout
 	acmd.Stderr = os.Stderr
@@ -512,6 +514,66 @@ func (tfs tFsys) Write(file, s string) {
 	fid.Close()
 }
 
+// TestFSysHeadless checks that the file server and the Edit language
+// work without a display.
+func TestFSysHeadless(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+	a := startAcme(t, "-headless")
+	defer a.Cleanup()
+
+	w, err := acme.New()
+	if err != nil {
+		t.Fatalf("Creating new window failed: %v\n", err)
+	}
+	defer w.Del(true)
+
+	if err := w.Name("/edward/headless"); err != nil {
+		t.Fatalf("Setting window name failed: %v", err)
+	}
+	if _, err := w.Write("body", []byte("one two\nthree two\n")); err != nil {
+		t.Fatalf("Writing body failed: %v", err)
+	}
+
+	if err := w.Ctl("clean"); err != nil {
+		t.Fatalf("Marking window clean failed: %v", err)
+	}
+
+	// Execute an Edit command written in the tag.
+	const cmd = "Edit ,s/two/2/g"
+	if _, err := w.Write("tag", []byte(cmd)); err != nil {
+		t.Fatalf("Writing tag failed: %v", err)
+	}
+	tag, err := w.ReadAll("tag")
+	if err != nil {
+		t.Fatalf("Reading tag failed: %v", err)
+	}
+	q1 := utf8.RuneCount(tag)
+	err = w.WriteEvent(&acme.Event{C1: 'M', C2: 'x', Q0: q1 - len(cmd), Q1: q1})
+	if err != nil {
+		t.Fatalf("Writing event failed: %v", err)
+	}
+	body, err := w.ReadAll("body")
+	if err != nil {
+		t.Fatalf("Reading body failed: %v", err)
+	}
+	if got, want := string(body), "one 2\nthree 2\n"; got != want {
+		t.Errorf("body is %q; expected %q", got, want)
+	}
+
+	if err := w.Addr("/three/"); err != nil {
+		t.Fatalf("Setting address failed: %v", err)
+	}
+	q0, q1, err := w.ReadAddr()
+	if err != nil {
+		t.Fatalf("Reading address failed: %v", err)
+	}
+	if q0 != 6 || q1 != 11 {
+		t.Errorf("address is %v,%v; expected 6,11", q0, q1)
+	}
+}
+
 func TestGetuser(t *testing.T) {
 	if getuser() == "" {
 		t.Errorf("Didn't get a username")
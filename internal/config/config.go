@@ -0,0 +1,346 @@
+// Package config parses Edward's configuration file.
+//
+// The file consists of lines of space-separated words. Blank lines and
+// lines starting with '#' are ignored. The first word of a line names
+// a setting:
+//
+//	font name               variable-width font
+//	fixedfont name          fixed-width font
+//	shell name              shell used to run commands
+//	tabstop n               width of a tab, in units of the '0' character
+//	tabexpand true|false    whether typed tabs are expanded to spaces
+//	ext .suffix setting...  tabstop and tabexpand settings for files
+//	                        whose names end in suffix
+//	color name #rrggbb      colour of part of a window (see Colors)
+//	tag type text...        text right of the bar in the tags of new
+//	                        windows of type file, dir, errors or find
+//	lazyload size           size in bytes, optionally followed by K, M
+//	                        or G, above which files are loaded in the
+//	                        background
+//	complete .suffix cmd... command printing completions, one per line,
+//	                        for files whose names end in suffix
+//
+// For example:
+//
+//	font /mnt/font/GoRegular/13a/font
+//	tabstop 4
+//	ext .go tabstop 8 tabexpand false
+//	ext .py tabexpand true
+//	color bodyback #ffffea
+//	tag dir Look Edit Find
+//	complete .go L comp
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Colors lists the names accepted by the color setting. The tag and body
+// colours are the background, the background of selected text, the border
+// of the scroll bar and button, the text and the selected text.
+var Colors = []string{
+	"tagback", "taghigh", "tagbord", "tagtext", "taghtext",
+	"bodyback", "bodyhigh", "bodybord", "bodytext", "bodyhtext",
+}
+
+// TagTypes lists the window types accepted by the tag setting.
+var TagTypes = []string{"file", "dir", "errors", "find"}
+
+// Tabs holds tab settings. A zero Stop or nil Expand means the setting is
+// not given.
+type Tabs struct {
+	Stop   int
+	Expand *bool
+}
+
+// Merge returns t with settings not given in t taken from u.
+func (t Tabs) Merge(u Tabs) Tabs {
+	if t.Stop == 0 {
+		t.Stop = u.Stop
+	}
+	if t.Expand == nil {
+		t.Expand = u.Expand
+	}
+	return t
+}
+
+// Config holds the settings in a configuration file. Settings not given in
+// the file are left as zero values.
+type Config struct {
+	VarFont   string
+	FixedFont string
+	Shell     string
+	Tabs      Tabs
+	Ext       map[string]Tabs   // by file name suffix
+	Colors    map[string]uint32 // RGBA colours, by name from Colors
+	Tags      map[string]string // by window type from TagTypes
+	LazyLoad  int64             // in bytes
+	Complete  map[string]string // completion commands, by file name suffix
+}
+
+// Dir returns the user's configuration directory, as os.UserConfigDir
+// does from Go 1.13 on.
+func Dir() (string, error) {
+	var dir string
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("AppData")
+		if dir == "" {
+			return "", errors.New("%AppData% is not defined")
+		}
+	case "darwin":
+		dir = os.Getenv("HOME")
+		if dir == "" {
+			return "", errors.New("$HOME is not defined")
+		}
+		dir += "/Library/Application Support"
+	case "plan9":
+		dir = os.Getenv("home")
+		if dir == "" {
+			return "", errors.New("$home is not defined")
+		}
+		dir += "/lib"
+	default:
+		dir = os.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = os.Getenv("HOME")
+			if dir == "" {
+				return "", errors.New("neither $XDG_CONFIG_HOME nor $HOME are defined")
+			}
+			dir += "/.config"
+		}
+	}
+	return dir, nil
+}
+
+// DefaultPath returns the path of the configuration file:
+// edward/config in the user's configuration directory.
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "edward", "config"), nil
+}
+
+// Load parses the configuration file filename. A missing file is not an
+// error; it yields an empty Config.
+func Load(filename string) (*Config, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v:%v", filename, err)
+	}
+	return c, nil
+}
+
+// Parse parses a configuration file read from r.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if err := c.parseLine(line); err != nil {
+			return nil, fmt.Errorf("%d: %v", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) parseLine(line string) error {
+	words := strings.Fields(line)
+	key, args := words[0], words[1:]
+	switch key {
+	case "font", "fixedfont", "shell":
+		if len(args) != 1 {
+			return fmt.Errorf("%v needs one argument", key)
+		}
+		switch key {
+		case "font":
+			c.VarFont = args[0]
+		case "fixedfont":
+			c.FixedFont = args[0]
+		case "shell":
+			c.Shell = args[0]
+		}
+	case "tabstop", "tabexpand":
+		return c.Tabs.parse(words)
+	case "ext":
+		if len(args) < 3 || !strings.HasPrefix(args[0], ".") {
+			return fmt.Errorf("usage: ext .suffix setting value...")
+		}
+		if c.Ext == nil {
+			c.Ext = make(map[string]Tabs)
+		}
+		t := c.Ext[args[0]]
+		if err := t.parse(args[1:]); err != nil {
+			return err
+		}
+		c.Ext[args[0]] = t
+	case "color":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: color name #rrggbb")
+		}
+		if !contains(Colors, args[0]) {
+			return fmt.Errorf("unknown color %q", args[0])
+		}
+		v, err := parseColor(args[1])
+		if err != nil {
+			return err
+		}
+		if c.Colors == nil {
+			c.Colors = make(map[string]uint32)
+		}
+		c.Colors[args[0]] = v
+	case "tag":
+		if len(args) < 1 || !contains(TagTypes, args[0]) {
+			return fmt.Errorf("usage: tag file|dir|errors|find text...")
+		}
+		if c.Tags == nil {
+			c.Tags = make(map[string]string)
+		}
+		c.Tags[args[0]] = strings.Join(args[1:], " ")
+	case "lazyload":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: lazyload size")
+		}
+		n, err := parseSize(args[0])
+		if err != nil {
+			return err
+		}
+		c.LazyLoad = n
+	case "complete":
+		if len(args) < 2 || !strings.HasPrefix(args[0], ".") {
+			return fmt.Errorf("usage: complete .suffix command...")
+		}
+		if c.Complete == nil {
+			c.Complete = make(map[string]string)
+		}
+		c.Complete[args[0]] = strings.Join(args[1:], " ")
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// parseSize parses a positive number of bytes, optionally followed by
+// K, M or G for kibibytes, mebibytes or gibibytes.
+func parseSize(s string) (int64, error) {
+	shift := uint(0)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		shift = 10
+	case strings.HasSuffix(s, "M"):
+		shift = 20
+	case strings.HasSuffix(s, "G"):
+		shift = 30
+	}
+	num := s
+	if shift > 0 {
+		num = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > 1<<(63-shift)-1 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return n << shift, nil
+}
+
+// parse sets t from pairs of words naming a tab setting and its value.
+func (t *Tabs) parse(words []string) error {
+	if len(words)%2 != 0 {
+		return fmt.Errorf("%v needs a value", words[len(words)-1])
+	}
+	for i := 0; i < len(words); i += 2 {
+		switch key, val := words[i], words[i+1]; key {
+		case "tabstop":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("bad tabstop %q", val)
+			}
+			t.Stop = n
+		case "tabexpand":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("bad tabexpand %q", val)
+			}
+			t.Expand = &b
+		default:
+			return fmt.Errorf("unknown tab setting %q", key)
+		}
+	}
+	return nil
+}
+
+// parseColor parses a colour written as #rrggbb or #rrggbbaa.
+func parseColor(s string) (uint32, error) {
+	if !strings.HasPrefix(s, "#") || (len(s) != 7 && len(s) != 9) {
+		return 0, fmt.Errorf("bad color %q", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad color %q", s)
+	}
+	if len(s) == 7 {
+		v = v<<8 | 0xFF
+	}
+	return uint32(v), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TabsFor returns the tab settings for the file name: those for the longest
+// matching suffix merged with the global ones.
+func (c *Config) TabsFor(name string) Tabs {
+	var best string
+	for suffix := range c.Ext {
+		if strings.HasSuffix(name, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return c.Tabs
+	}
+	return c.Ext[best].Merge(c.Tabs)
+}
+
+// CompleteFor returns the completion command for the file name: the one
+// for the longest matching suffix, or "" if there is none.
+func (c *Config) CompleteFor(name string) string {
+	var best string
+	for suffix := range c.Complete {
+		if strings.HasSuffix(name, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	return c.Complete[best]
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func boolp(b bool) *bool { return &b }
+
+func TestParse(t *testing.T) {
+	const input = `
+# Edward configuration
+font /lib/font/bit/lucsans/euro.8.font
+fixedfont /lib/font/bit/lucm/unicode.9.font
+shell bash
+tabstop 4
+tabexpand false
+ext .go tabstop 8
+ext .py tabstop 4 tabexpand true
+ext .go tabexpand false
+color bodyback #ffffea
+color tagtext #000000ff
+tag dir Look Edit Find
+tag errors
+lazyload 16M
+complete .go L comp
+complete .go L comp -e
+complete .c ctags-complete
+`
+	want := &Config{
+		VarFont:   "/lib/font/bit/lucsans/euro.8.font",
+		FixedFont: "/lib/font/bit/lucm/unicode.9.font",
+		Shell:     "bash",
+		Tabs:      Tabs{Stop: 4, Expand: boolp(false)},
+		Ext: map[string]Tabs{
+			".go": {Stop: 8, Expand: boolp(false)},
+			".py": {Stop: 4, Expand: boolp(true)},
+		},
+		Colors: map[string]uint32{
+			"bodyback": 0xFFFFEAFF,
+			"tagtext":  0x000000FF,
+		},
+		LazyLoad: 16 << 20,
+		Complete: map[string]string{
+			".go": "L comp -e",
+			".c":  "ctags-complete",
+		},
+		Tags: map[string]string{
+			"dir":    "Look Edit Find",
+			"errors": "",
+		},
+	}
+	c, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("Parse mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, tc := range []struct {
+		input, err string
+	}{
+		{"fnot x", `1: unknown setting "fnot"`},
+		{"\nfont", "2: font needs one argument"},
+		{"tabstop -1", `1: bad tabstop "-1"`},
+		{"tabexpand maybe", `1: bad tabexpand "maybe"`},
+		{"tabstop 4 tabexpand", "1: tabexpand needs a value"},
+		{"ext go tabstop 4", "1: usage: ext .suffix setting value..."},
+		{"ext .go font x", `1: unknown tab setting "font"`},
+		{"color back #fff", `1: unknown color "back"`},
+		{"color tagback #fff", `1: bad color "#fff"`},
+		{"color tagback #gggggg", `1: bad color "#gggggg"`},
+		{"tag column Look", "1: usage: tag file|dir|errors|find text..."},
+		{"lazyload", "1: usage: lazyload size"},
+		{"lazyload 0", `1: bad size "0"`},
+		{"lazyload 1T", `1: bad size "1T"`},
+		{"lazyload xM", `1: bad size "xM"`},
+		{"complete .go", "1: usage: complete .suffix command..."},
+		{"complete go L comp", "1: usage: complete .suffix command..."},
+	} {
+		_, err := Parse(strings.NewReader(tc.input))
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded; expected error %q", tc.input, tc.err)
+			continue
+		}
+		if err.Error() != tc.err {
+			t.Errorf("Parse(%q) failed with %q; expected %q", tc.input, err, tc.err)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(os.TempDir(), "edward-no-such-config"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if diff := cmp.Diff(&Config{}, c); diff != "" {
+		t.Errorf("Load mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTabsFor(t *testing.T) {
+	c := &Config{
+		Tabs: Tabs{Stop: 4, Expand: boolp(false)},
+		Ext: map[string]Tabs{
+			".go":      {Stop: 8},
+			".py":      {Expand: boolp(true)},
+			"_test.go": {Stop: 2},
+		},
+	}
+	for _, tc := range []struct {
+		name string
+		want Tabs
+	}{
+		{"/a/b.c", Tabs{Stop: 4, Expand: boolp(false)}},
+		{"/a/b.go", Tabs{Stop: 8, Expand: boolp(false)}},
+		{"/a/b.py", Tabs{Stop: 4, Expand: boolp(true)}},
+		{"/a/b_test.go", Tabs{Stop: 2, Expand: boolp(false)}},
+	} {
+		if diff := cmp.Diff(tc.want, c.TabsFor(tc.name)); diff != "" {
+			t.Errorf("TabsFor(%q) mismatch (-want +got):\n%s", tc.name, diff)
+		}
+	}
+}
+
+func TestCompleteFor(t *testing.T) {
+	c := &Config{
+		Complete: map[string]string{
+			".go":      "L comp",
+			".test.go": "testcomp",
+		},
+	}
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"/a/b.c", ""},
+		{"/a/b.go", "L comp"},
+		{"/a/b.test.go", "testcomp"},
+	} {
+		if got := c.CompleteFor(tc.name); got != tc.want {
+			t.Errorf("CompleteFor(%q) is %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "plan9":
+		t.Skipf("configuration directory doesn't depend on $XDG_CONFIG_HOME on %v", runtime.GOOS)
+	}
+	xdg, home := os.Getenv("XDG_CONFIG_HOME"), os.Getenv("HOME")
+	defer os.Setenv("XDG_CONFIG_HOME", xdg)
+	defer os.Setenv("HOME", home)
+
+	os.Setenv("HOME", "/home/glenda")
+	for _, tc := range []struct {
+		xdg, want string
+	}{
+		{"/xdg", "/xdg"},
+		{"", "/home/glenda/.config"},
+	} {
+		os.Setenv("XDG_CONFIG_HOME", tc.xdg)
+		dir, err := Dir()
+		if err != nil {
+			t.Fatalf("Dir with XDG_CONFIG_HOME=%q failed: %v", tc.xdg, err)
+		}
+		if dir != tc.want {
+			t.Errorf("Dir with XDG_CONFIG_HOME=%q is %q; want %q", tc.xdg, dir, tc.want)
+		}
+	}
+}
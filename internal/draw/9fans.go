@@ -3,7 +3,12 @@
 package draw
 
 import (
+	"errors"
+	"reflect"
+	"unsafe"
+
 	draw "9fans.net/go/draw"
+	"9fans.net/go/draw/drawfcall"
 )
 
 const (
@@ -53,6 +58,11 @@ func Main(f func(*Device)) {
 
 type Device struct{}
 
+// NewDisplay opens a new OS window. Each window is a separate devdraw
+// connection, and so a separate devdraw process: the protocol has no way
+// to open a second window on a connection.
+// TODO: open every window on one connection, sharing its fonts and
+// images, once devdraw can serve several windows.
 func (dev *Device) NewDisplay(errch chan<- error, fontname, label, winsize string) (Display, error) {
 	d, err := Init(errch, fontname, label, winsize)
 	if err != nil {
@@ -60,3 +70,21 @@ func (dev *Device) NewDisplay(errch chan<- error, fontname, label, winsize strin
 	}
 	return &displayImpl{d}, nil
 }
+
+// FontCache returns the cache for the fonts of a new display. Fonts are
+// held by devdraw for one connection, so each display has its own.
+func (dev *Device) FontCache() *FontCache {
+	return NewFontCache()
+}
+
+// setLabel sends the label message to devdraw. The draw package doesn't
+// export its connection yet, so we have to dig it out of the Display,
+// checking that it still holds one.
+// TODO(fhs): use (*draw.Display).SetLabel once the fork has it.
+func setLabel(d *drawDisplay, label string) error {
+	v := reflect.ValueOf(d).Elem().FieldByName("conn")
+	if !v.IsValid() || v.Type() != reflect.TypeOf((*drawfcall.Conn)(nil)) || v.IsNil() {
+		return errors.New("can't set label: no connection to devdraw")
+	}
+	return (*drawfcall.Conn)(unsafe.Pointer(v.Pointer())).Label(label)
+}
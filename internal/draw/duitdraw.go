@@ -50,12 +50,13 @@ var Init = draw.Init
 
 func Main(f func(dev *Device)) {
 	draw.Main(func(dd *drawDevice) {
-		f(&Device{dd})
+		f(&Device{dd, NewFontCache()})
 	})
 }
 
 type Device struct {
 	*drawDevice
+	fonts *FontCache
 }
 
 func (dev *Device) NewDisplay(errch chan<- error, fontname, label, winsize string) (Display, error) {
@@ -65,3 +66,16 @@ func (dev *Device) NewDisplay(errch chan<- error, fontname, label, winsize strin
 	}
 	return &displayImpl{d}, nil
 }
+
+// FontCache returns the cache for the fonts of a new display. All displays
+// are drawn in this process and fonts aren't tied to one, so they share a
+// cache.
+func (dev *Device) FontCache() *FontCache {
+	return dev.fonts
+}
+
+// setLabel does nothing: duitdraw doesn't support changing the title of a
+// window once it's open.
+func setLabel(d *drawDisplay, label string) error {
+	return nil
+}
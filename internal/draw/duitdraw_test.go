@@ -0,0 +1,38 @@
+// +build duitdraw
+
+package draw
+
+import (
+	"testing"
+
+	draw "github.com/ktye/duitdraw"
+)
+
+// BenchmarkWindowFonts measures opening the fonts of 50 windows, each
+// with its own cache as with devdraw, or sharing one as with duitdraw.
+func BenchmarkWindowFonts(b *testing.B) {
+	const nwin = 50
+	d := &displayImpl{&drawDisplay{DPI: draw.DefaultDPI}}
+	for _, bc := range []struct {
+		name  string
+		cache func(shared *FontCache) *FontCache
+	}{
+		{"PerWindow", func(*FontCache) *FontCache { return NewFontCache() }},
+		{"Shared", func(shared *FontCache) *FontCache { return shared }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				shared := NewFontCache()
+				for j := 0; j < nwin; j++ {
+					c := bc.cache(shared)
+					for _, name := range []string{"", "@14pt"} {
+						if _, err := c.Open(d, name); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package draw
+
+import "sync"
+
+// A FontCache holds the fonts opened by name and resolution, so that each
+// is opened only once. It is safe for concurrent use.
+type FontCache struct {
+	mu    sync.Mutex
+	fonts map[fontKey]Font
+}
+
+// A fontKey identifies a font in a FontCache. The same name gives glyphs
+// of different sizes on displays of different resolutions.
+type fontKey struct {
+	name string
+	dpi  int
+}
+
+// NewFontCache returns an empty font cache.
+func NewFontCache() *FontCache {
+	return &FontCache{fonts: make(map[fontKey]Font)}
+}
+
+// Open returns the font called name, opening it on the display d if it
+// isn't in the cache for the resolution of d.
+func (c *FontCache) Open(d Display, name string) (Font, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := fontKey{name, d.DPI()}
+	if f, ok := c.fonts[k]; ok {
+		return f, nil
+	}
+	f, err := d.OpenFont(name)
+	if err != nil {
+		return nil, err
+	}
+	c.fonts[k] = f
+	return f, nil
+}
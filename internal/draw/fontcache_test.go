@@ -0,0 +1,50 @@
+package draw
+
+import "testing"
+
+type countingDisplay struct {
+	Display
+	dpi    int
+	opened []string
+}
+
+func (d *countingDisplay) DPI() int { return d.dpi }
+
+type nameFont struct {
+	Font
+	name string
+}
+
+func (d *countingDisplay) OpenFont(name string) (Font, error) {
+	d.opened = append(d.opened, name)
+	return &nameFont{name: name}, nil
+}
+
+func TestFontCache(t *testing.T) {
+	c := NewFontCache()
+	d1, d2, hidpi := &countingDisplay{dpi: 100}, &countingDisplay{dpi: 100}, &countingDisplay{dpi: 200}
+	for _, tc := range []struct {
+		d    *countingDisplay
+		name string
+	}{
+		{d1, "a"},
+		{d1, "a"},
+		{d2, "a"},
+		{d2, "b"},
+		{hidpi, "a"},
+	} {
+		f, err := c.Open(tc.d, tc.name)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", tc.name, err)
+		}
+		if got := f.(*nameFont).name; got != tc.name {
+			t.Errorf("Open(%q) returned font %q", tc.name, got)
+		}
+	}
+	if len(d1.opened) != 1 || len(d2.opened) != 1 {
+		t.Errorf("fonts opened are %q and %q; want one on each display", d1.opened, d2.opened)
+	}
+	if len(hidpi.opened) != 1 {
+		t.Errorf("fonts opened on the display of another resolution are %q; want one", hidpi.opened)
+	}
+}
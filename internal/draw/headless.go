@@ -0,0 +1,137 @@
+package draw
+
+import (
+	"image"
+	"sync"
+	"unicode/utf8"
+)
+
+// Geometry of the headless screen and of the characters of headless fonts.
+const (
+	headlessWidth      = 1024
+	headlessHeight     = 768
+	headlessFontWidth  = 8
+	headlessFontHeight = 16
+)
+
+// The snarf buffer is shared by all headless displays, so that
+// text cut in one window can be pasted in another.
+var headlessSnarf struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+var _ = Display((*headlessDisplay)(nil))
+
+// headlessDisplay implements Display without a connection to a window system.
+type headlessDisplay struct {
+	screen Image
+}
+
+// NewHeadlessDisplay returns a Display that is not connected to any window
+// system. Drawing operations are discarded, fonts are fixed-width, the
+// keyboard and mouse never produce any events, and the snarf buffer is
+// kept in memory.
+func NewHeadlessDisplay() Display {
+	d := &headlessDisplay{}
+	d.screen = &headlessImage{d: d, r: image.Rect(0, 0, headlessWidth, headlessHeight)}
+	return d
+}
+
+func (d *headlessDisplay) ScreenImage() Image { return d.screen }
+func (d *headlessDisplay) White() Image       { return d.color() }
+func (d *headlessDisplay) Black() Image       { return d.color() }
+func (d *headlessDisplay) Opaque() Image      { return d.color() }
+func (d *headlessDisplay) Transparent() Image { return d.color() }
+
+func (d *headlessDisplay) color() Image {
+	return &headlessImage{d: d, r: image.Rect(0, 0, 1, 1)}
+}
+
+// InitKeyboard returns a Keyboardctl whose channel never delivers a key.
+func (d *headlessDisplay) InitKeyboard() *Keyboardctl {
+	return &Keyboardctl{C: make(chan rune)}
+}
+
+// InitMouse returns a Mousectl whose channels never deliver an event.
+func (d *headlessDisplay) InitMouse() *Mousectl {
+	return &Mousectl{
+		C:      make(chan Mouse),
+		Resize: make(chan bool),
+	}
+}
+
+func (d *headlessDisplay) OpenFont(name string) (Font, error) {
+	return &headlessFont{name: name}, nil
+}
+
+func (d *headlessDisplay) AllocImage(r image.Rectangle, pix Pix, repl bool, val Color) (Image, error) {
+	return &headlessImage{d: d, r: r, pix: pix}, nil
+}
+
+func (d *headlessDisplay) AllocImageMix(color1, color3 Color) Image { return d.color() }
+func (d *headlessDisplay) Attach(ref int) error                     { return nil }
+func (d *headlessDisplay) Flush() error                             { return nil }
+func (d *headlessDisplay) ScaleSize(n int) int                      { return n }
+func (d *headlessDisplay) DPI() int                                 { return 100 }
+func (d *headlessDisplay) MoveTo(pt image.Point) error              { return nil }
+func (d *headlessDisplay) SetCursor(c *Cursor) error                { return nil }
+func (d *headlessDisplay) SetLabel(label string) error              { return nil }
+func (d *headlessDisplay) Close() error                             { return nil }
+
+// ReadSnarf reads the snarf buffer into buf, returning the number of bytes
+// read and the total size of the snarf buffer. If buf is too short, nothing
+// is read, as with the devdraw implementation.
+func (d *headlessDisplay) ReadSnarf(buf []byte) (int, int, error) {
+	headlessSnarf.mu.Lock()
+	defer headlessSnarf.mu.Unlock()
+
+	if len(buf) < len(headlessSnarf.buf) {
+		return 0, len(headlessSnarf.buf), nil
+	}
+	n := copy(buf, headlessSnarf.buf)
+	return n, n, nil
+}
+
+func (d *headlessDisplay) WriteSnarf(data []byte) error {
+	headlessSnarf.mu.Lock()
+	defer headlessSnarf.mu.Unlock()
+
+	headlessSnarf.buf = append(headlessSnarf.buf[:0], data...)
+	return nil
+}
+
+var _ = Image((*headlessImage)(nil))
+
+// headlessImage implements Image. Drawing on it has no effect.
+type headlessImage struct {
+	d   *headlessDisplay
+	r   image.Rectangle
+	pix Pix
+}
+
+func (i *headlessImage) Display() Display                                             { return i.d }
+func (i *headlessImage) Pix() Pix                                                     { return i.pix }
+func (i *headlessImage) R() image.Rectangle                                           { return i.r }
+func (i *headlessImage) Draw(r image.Rectangle, src, mask Image, p1 image.Point)      {}
+func (i *headlessImage) Border(r image.Rectangle, n int, color Image, sp image.Point) {}
+func (i *headlessImage) Free() error                                                  { return nil }
+
+func (i *headlessImage) Bytes(pt image.Point, src Image, sp image.Point, f Font, b []byte) image.Point {
+	return pt.Add(image.Pt(f.BytesWidth(b), 0))
+}
+
+var _ = Font((*headlessFont)(nil))
+
+// headlessFont implements Font as a fixed-width font.
+type headlessFont struct {
+	name string
+}
+
+func (f *headlessFont) Name() string            { return f.name }
+func (f *headlessFont) Height() int             { return headlessFontHeight }
+func (f *headlessFont) BytesWidth(b []byte) int { return headlessFontWidth * utf8.RuneCount(b) }
+func (f *headlessFont) RunesWidth(r []rune) int { return headlessFontWidth * len(r) }
+func (f *headlessFont) StringWidth(s string) int {
+	return headlessFontWidth * utf8.RuneCountInString(s)
+}
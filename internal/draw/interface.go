@@ -17,10 +17,12 @@ type Display interface {
 	Attach(ref int) error
 	Flush() error
 	ScaleSize(n int) int
+	DPI() int
 	ReadSnarf(buf []byte) (int, int, error)
 	WriteSnarf(data []byte) error
 	MoveTo(pt image.Point) error
 	SetCursor(c *Cursor) error
+	SetLabel(label string) error
 	Close() error
 }
 
@@ -56,6 +58,8 @@ func (d *displayImpl) Black() Image       { return &imageImpl{d.drawDisplay.Blac
 func (d *displayImpl) Opaque() Image      { return &imageImpl{d.drawDisplay.Opaque} }
 func (d *displayImpl) Transparent() Image { return &imageImpl{d.drawDisplay.Transparent} }
 
+func (d *displayImpl) DPI() int { return d.drawDisplay.DPI }
+
 func (d *displayImpl) OpenFont(name string) (Font, error) {
 	f, err := d.drawDisplay.OpenFont(name)
 	if err != nil {
@@ -76,6 +80,11 @@ func (d *displayImpl) AllocImageMix(color1, color3 Color) Image {
 	return &imageImpl{d.drawDisplay.AllocImageMix(color1, color3)}
 }
 
+// SetLabel sets the title of the window showing the display.
+func (d *displayImpl) SetLabel(label string) error {
+	return setLabel(d.drawDisplay, label)
+}
+
 // imageImpl implements the Image interface.
 type imageImpl struct {
 	*drawImage
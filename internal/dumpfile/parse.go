@@ -8,11 +8,15 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"image"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 )
 
-const version = 1
+// Version 2 added Window.Geometry. Older dump files can still be loaded.
+const version = 2
 
 // WindowType defines the type of window.
 type WindowType int
@@ -48,6 +52,9 @@ type Window struct {
 	Position float64 // Position within the column (in percentage)
 	Font     string  `json:",omitempty"` // Font name or path
 
+	// Size and position of the OS window showing this window
+	Geometry *Geometry `json:",omitempty"`
+
 	// ctl line has these but there is no point storing them:
 	//ID	int	// we regenerate window IDs when loading
 	//TagLen	int	// redundant
@@ -66,6 +73,58 @@ type Window struct {
 	ExecCommand string `json:",omitempty"` // Command to execute
 }
 
+// Geometry stores the size, in pixels, and placement of an OS window.
+type Geometry struct {
+	Width    int
+	Height   int
+	Position *image.Point `json:",omitempty"` // Top-left corner, if known
+	Screen   string       `json:",omitempty"` // Screen or workspace, if known
+}
+
+// ParseWinsize parses a window size and position written as
+// WidthxHeight[@X,Y], the format of Edwood's -W flag.
+func ParseWinsize(s string) (*Geometry, error) {
+	bad := fmt.Errorf("bad window size %q", s)
+	size := s
+	pos := ""
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		size, pos = s[:i], s[i+1:]
+	}
+	var g Geometry
+	if !parsePair(size, "x", &g.Width, &g.Height) || g.Width <= 0 || g.Height <= 0 {
+		return nil, bad
+	}
+	if pos != "" {
+		var p image.Point
+		if !parsePair(pos, ",", &p.X, &p.Y) {
+			return nil, bad
+		}
+		g.Position = &p
+	}
+	return &g, nil
+}
+
+func parsePair(s, sep string, a, b *int) bool {
+	f := strings.Split(s, sep)
+	if len(f) != 2 {
+		return false
+	}
+	var err1, err2 error
+	*a, err1 = strconv.Atoi(f[0])
+	*b, err2 = strconv.Atoi(f[1])
+	return err1 == nil && err2 == nil
+}
+
+// Winsize returns the size and position in g in the format parsed
+// by ParseWinsize.
+func (g *Geometry) Winsize() string {
+	s := fmt.Sprintf("%dx%d", g.Width, g.Height)
+	if g.Position != nil {
+		s += fmt.Sprintf("@%d,%d", g.Position.X, g.Position.Y)
+	}
+	return s
+}
+
 // Text is a UTF-8 encoded text with a substring selected
 // using rune-indexing (instead of byte-indexing).
 type Text struct {
@@ -97,8 +156,8 @@ func decode(r io.Reader) (*Content, error) {
 	if err != nil {
 		return nil, err
 	}
-	if vc.Version != version {
-		return nil, fmt.Errorf("dump file format %v; expected %v", vc.Version, version)
+	if vc.Version < 1 || vc.Version > version {
+		return nil, fmt.Errorf("dump file format %v; expected 1 to %v", vc.Version, version)
 	}
 	return vc.Content, nil
 }
@@ -2,9 +2,11 @@ package dumpfile
 
 import (
 	"bytes"
+	"image"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -80,3 +82,79 @@ func TestSaveLoad(t *testing.T) {
 		t.Errorf("content is %#v; expected %#v\n", c, tc)
 	}
 }
+
+func TestGeometryEncodeDecode(t *testing.T) {
+	tc := &Content{
+		Windows: []*Window{
+			{Type: Saved},
+			{Type: Saved, Geometry: &Geometry{Width: 800, Height: 600}},
+			{Type: Unsaved, Geometry: &Geometry{
+				Width:    640,
+				Height:   480,
+				Position: &image.Point{10, 20},
+				Screen:   "2",
+			}},
+		},
+	}
+	var b bytes.Buffer
+	if err := tc.encode(&b); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	c, err := decode(&b)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(tc, c) {
+		t.Errorf("content is %#v; expected %#v", c, tc)
+	}
+}
+
+func TestDecodeVersion(t *testing.T) {
+	for _, tc := range []struct {
+		dump string
+		err  string
+	}{
+		{`{"Version": 1, "Windows": [{"Type": 0, "Tag": {"Buffer": "/a Del"}}]}`, ""},
+		{`{"Version": 2, "Windows": [{"Type": 0, "Geometry": {"Width": 3, "Height": 4}}]}`, ""},
+		{`{"Version": 0}`, "dump file format 0; expected 1 to 2"},
+		{`{"Version": 3}`, "dump file format 3; expected 1 to 2"},
+	} {
+		_, err := decode(strings.NewReader(tc.dump))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.err {
+			t.Errorf("decode(%q) returned error %q; expected %q", tc.dump, got, tc.err)
+		}
+	}
+}
+
+func TestParseWinsize(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		g   *Geometry
+		err bool
+	}{
+		{"800x600", &Geometry{Width: 800, Height: 600}, false},
+		{"800x600@10,-20", &Geometry{Width: 800, Height: 600, Position: &image.Point{10, -20}}, false},
+		{"", nil, true},
+		{"800", nil, true},
+		{"0x600", nil, true},
+		{"800x600@10", nil, true},
+		{"axb", nil, true},
+	} {
+		g, err := ParseWinsize(tc.s)
+		if (err != nil) != tc.err {
+			t.Errorf("ParseWinsize(%q) returned error %v", tc.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(g, tc.g) {
+			t.Errorf("ParseWinsize(%q) is %#v; expected %#v", tc.s, g, tc.g)
+			continue
+		}
+		if g != nil && g.Winsize() != tc.s {
+			t.Errorf("Winsize of %#v is %q; expected %q", g, g.Winsize(), tc.s)
+		}
+	}
+}
@@ -15,6 +15,7 @@ var _ = draw.Display((*mockDisplay)(nil))
 // mockDisplay implements draw.Display.
 type mockDisplay struct {
 	snarfbuf []byte
+	label    string
 	mu       sync.Mutex
 }
 
@@ -39,6 +40,7 @@ func (d *mockDisplay) AllocImageMix(color1, color3 draw.Color) draw.Image {
 func (d *mockDisplay) Attach(ref int) error { return nil }
 func (d *mockDisplay) Flush() error         { return nil }
 func (d *mockDisplay) ScaleSize(n int) int  { return 0 }
+func (d *mockDisplay) DPI() int             { return 100 }
 func (d *mockDisplay) Close() error         { return nil }
 
 // ReadSnarf reads the snarf buffer into buf, returning the number of bytes read,
@@ -69,6 +71,23 @@ func (d *mockDisplay) WriteSnarf(data []byte) error {
 func (d *mockDisplay) MoveTo(pt image.Point) error    { return nil }
 func (d *mockDisplay) SetCursor(c *draw.Cursor) error { return nil }
 
+// SetLabel records the window title.
+func (d *mockDisplay) SetLabel(label string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.label = label
+	return nil
+}
+
+// Label returns the window title last set with SetLabel.
+func (d *mockDisplay) Label() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.label
+}
+
 var _ = draw.Image((*mockImage)(nil))
 
 // mockImage implements draw.Image.
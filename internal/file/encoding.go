@@ -0,0 +1,336 @@
+package file
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Charset is the character encoding of a file on disk.
+type Charset int
+
+const (
+	UTF8    Charset = iota // UTF-8 without byte order mark
+	UTF8BOM                // UTF-8 starting with a byte order mark
+	UTF16LE                // little-endian UTF-16 starting with a byte order mark
+	UTF16BE                // big-endian UTF-16 starting with a byte order mark
+	Latin1                 // ISO 8859-1
+	Hex                    // binary data, shown as a hex dump
+)
+
+var charsetNames = []string{
+	UTF8:    "utf-8",
+	UTF8BOM: "utf-8-bom",
+	UTF16LE: "utf-16le",
+	UTF16BE: "utf-16be",
+	Latin1:  "latin1",
+	Hex:     "hex",
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+func (c Charset) String() string {
+	if c < 0 || int(c) >= len(charsetNames) {
+		return fmt.Sprintf("Charset(%d)", int(c))
+	}
+	return charsetNames[c]
+}
+
+// Encoding describes how the text of a file is stored on disk.
+// The zero value is UTF-8 with newline line endings.
+type Encoding struct {
+	Charset Charset
+	CRLF    bool // lines end in carriage return and newline
+}
+
+// String returns the encoding in the form accepted by Parse.
+func (e Encoding) String() string {
+	s := e.Charset.String()
+	if e.CRLF {
+		s += " crlf"
+	}
+	return s
+}
+
+// Parse parses a space-separated list of a charset name and
+// "crlf" or "lf". Settings not given are taken from e.
+func (e Encoding) Parse(s string) (Encoding, error) {
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		switch w {
+		case "crlf":
+			e.CRLF = true
+		case "lf":
+			e.CRLF = false
+		default:
+			found := false
+			for c, name := range charsetNames {
+				if w == name {
+					e.Charset = Charset(c)
+					found = true
+				}
+			}
+			if !found {
+				return e, fmt.Errorf("unknown encoding %q", w)
+			}
+		}
+	}
+	return e, nil
+}
+
+// DetectEncoding guesses the encoding of the file contents b. A byte
+// order mark selects UTF-8 or UTF-16, contents with NUL bytes are taken
+// to be binary, and text that isn't valid UTF-8 is taken to be Latin-1.
+// Line endings are CRLF if every newline follows a carriage return.
+func DetectEncoding(b []byte) Encoding {
+	var e Encoding
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		e.Charset = UTF8BOM
+	case bytes.HasPrefix(b, bomUTF16LE):
+		e.Charset = UTF16LE
+	case bytes.HasPrefix(b, bomUTF16BE):
+		e.Charset = UTF16BE
+	case bytes.IndexByte(b, 0) >= 0:
+		e.Charset = Hex
+		return e
+	case !utf8.Valid(b):
+		e.Charset = Latin1
+	}
+	u := e.decodeCharset(b)
+	n := bytes.Count(u, []byte("\n"))
+	e.CRLF = n > 0 && bytes.Count(u, []byte("\r\n")) == n
+	return e
+}
+
+// Decode converts the file contents b in encoding e to UTF-8 text with
+// newline line endings.
+func (e Encoding) Decode(b []byte) []byte {
+	if e.Charset == Hex {
+		return hexdump(b)
+	}
+	b = e.decodeCharset(b)
+	if e.CRLF {
+		b = bytes.Replace(b, []byte("\r\n"), []byte("\n"), -1)
+	}
+	return b
+}
+
+func (e Encoding) decodeCharset(b []byte) []byte {
+	switch e.Charset {
+	case UTF8BOM:
+		return bytes.TrimPrefix(b, bomUTF8)
+	case UTF16LE, UTF16BE:
+		return decodeUTF16(bytes.TrimPrefix(b, bomUTF16(e.Charset)), e.Charset == UTF16BE)
+	case Latin1:
+		u := make([]byte, 0, len(b))
+		for _, c := range b {
+			u = appendRune(u, rune(c))
+		}
+		return u
+	}
+	return b
+}
+
+// Fits reports whether b, a part of the contents of a file, is in
+// encoding e, so that decoding it and encoding the result gives b back:
+// it must be valid UTF-8 for the UTF-8 charsets, decode to text without
+// NULs, and have a carriage return before every newline if e.CRLF.
+func (e Encoding) Fits(b []byte) bool {
+	if e.Charset == Hex {
+		return true
+	}
+	if (e.Charset == UTF8 || e.Charset == UTF8BOM) && !utf8.Valid(b) {
+		return false
+	}
+	u := e.decodeCharset(b)
+	if bytes.IndexByte(u, 0) >= 0 {
+		return false
+	}
+	return !e.CRLF || bytes.Count(u, []byte("\n")) == bytes.Count(u, []byte("\r\n"))
+}
+
+// appendRune appends the UTF-8 encoding of r to b.
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+func bomUTF16(c Charset) []byte {
+	if c == UTF16BE {
+		return bomUTF16BE
+	}
+	return bomUTF16LE
+}
+
+func decodeUTF16(b []byte, bigEndian bool) []byte {
+	u := make([]byte, 0, len(b))
+	unit := func(i int) rune {
+		return utf16unit(b[i:i+2], bigEndian)
+	}
+	for i := 0; i+1 < len(b); i += 2 {
+		r := unit(i)
+		if utf16.IsSurrogate(r) && i+3 < len(b) {
+			if r1 := utf16.DecodeRune(r, unit(i+2)); r1 != utf8.RuneError {
+				r = r1
+				i += 2
+			}
+		}
+		u = appendRune(u, r)
+	}
+	return u
+}
+
+// Encode converts UTF-8 text with newline line endings to encoding e.
+// It fails if the text contains characters that e can't represent.
+func (e Encoding) Encode(b []byte) ([]byte, error) {
+	if e.Charset == Hex {
+		return unhexdump(b)
+	}
+	if e.CRLF {
+		b = bytes.Replace(b, []byte("\n"), []byte("\r\n"), -1)
+	}
+	switch e.Charset {
+	case UTF8BOM:
+		return append(append([]byte(nil), bomUTF8...), b...), nil
+	case UTF16LE, UTF16BE:
+		out := append([]byte(nil), bomUTF16(e.Charset)...)
+		put := func(r rune) {
+			if e.Charset == UTF16BE {
+				out = append(out, byte(r>>8), byte(r))
+			} else {
+				out = append(out, byte(r), byte(r>>8))
+			}
+		}
+		for _, r := range string(b) {
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				put(r1)
+				put(r2)
+				continue
+			}
+			put(r)
+		}
+		return out, nil
+	case Latin1:
+		out := make([]byte, 0, len(b))
+		for _, r := range string(b) {
+			if r > 0xFF {
+				return nil, fmt.Errorf("can't encode %U in %v", r, e.Charset)
+			}
+			out = append(out, byte(r))
+		}
+		return out, nil
+	}
+	return b, nil
+}
+
+// hexdump returns a hex dump of b. Each line shows the offset, up to
+// 16 bytes in hex and the same bytes as ASCII:
+//
+//	00000000: 48 65 6c 6c 6f 2c 20 77  6f 72 6c 64 21 0a 00 00  |Hello, world!...|
+func hexdump(b []byte) []byte {
+	var out bytes.Buffer
+	for off := 0; off < len(b); off += 16 {
+		line := b[off:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		fmt.Fprintf(&out, "%08x:", off)
+		for i := 0; i < 16; i++ {
+			if i == 8 {
+				out.WriteByte(' ')
+			}
+			if i < len(line) {
+				fmt.Fprintf(&out, " %02x", line[i])
+			} else {
+				out.WriteString("   ")
+			}
+		}
+		out.WriteString("  |")
+		for _, c := range line {
+			if c < ' ' || c > '~' {
+				c = '.'
+			}
+			out.WriteByte(c)
+		}
+		out.WriteString("|\n")
+	}
+	return out.Bytes()
+}
+
+// unhexdump returns the bytes in the hex dump b. Only the hex column
+// matters: the offset before the colon and the ASCII column after the
+// bar are ignored, so bytes may be changed, added or removed by editing
+// the hex column alone.
+func unhexdump(b []byte) ([]byte, error) {
+	var out []byte
+	for n, line := range strings.Split(string(b), "\n") {
+		if i := strings.IndexByte(line, '|'); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[i+1:]
+		}
+		for _, f := range strings.Fields(line) {
+			h, err := hex.DecodeString(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad hex %q", n+1, f)
+			}
+			out = append(out, h...)
+		}
+	}
+	return out, nil
+}
+
+// Cut returns the length of the longest prefix of b that can be decoded
+// apart from the rest of b, so that a file can be decoded a piece at a
+// time. The prefix doesn't end inside a character or a CRLF line ending.
+func (e Encoding) Cut(b []byte) int {
+	n := len(b)
+	unit := 1
+	switch e.Charset {
+	case UTF8, UTF8BOM:
+		// Back up over an incomplete last character.
+		for i := 1; i <= utf8.UTFMax && i <= n; i++ {
+			if utf8.RuneStart(b[n-i]) {
+				if !utf8.FullRune(b[n-i:]) {
+					n -= i
+				}
+				break
+			}
+		}
+	case UTF16LE, UTF16BE:
+		unit = 2
+		n &^= 1
+		if n >= 2 {
+			if r := utf16unit(b[n-2:n], e.Charset == UTF16BE); 0xD800 <= r && r < 0xDC00 {
+				n -= 2 // first half of a surrogate pair
+			}
+		}
+	case Hex:
+		n -= n % 16
+	}
+	if e.CRLF && n >= unit && utf16unit(b[n-unit:n], e.Charset == UTF16BE) == '\r' {
+		n -= unit
+	}
+	return n
+}
+
+// utf16unit returns the UTF-16 code unit at the start of b, or the first
+// byte if b is shorter than a code unit.
+func utf16unit(b []byte, bigEndian bool) rune {
+	switch {
+	case len(b) < 2:
+		return rune(b[0])
+	case bigEndian:
+		return rune(b[0])<<8 | rune(b[1])
+	}
+	return rune(b[1])<<8 | rune(b[0])
+}
@@ -0,0 +1,159 @@
+package file
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		enc  Encoding
+		text string
+	}{
+		{"", Encoding{}, ""},
+		{"hello\nworld\n", Encoding{}, "hello\nworld\n"},
+		{"hello\r\nworld\r\n", Encoding{CRLF: true}, "hello\nworld\n"},
+		{"hello\r\nworld\n", Encoding{}, "hello\r\nworld\n"},
+		{"no newline\r", Encoding{}, "no newline\r"},
+		{"\xEF\xBB\xBFbom\n", Encoding{Charset: UTF8BOM}, "bom\n"},
+		{"caf\xe9\r\n", Encoding{Charset: Latin1, CRLF: true}, "café\n"},
+		{"\xFF\xFEh\x00i\x00\r\x00\n\x00", Encoding{Charset: UTF16LE, CRLF: true}, "hi\n"},
+		{"\xFE\xFF\x00h\x00\xe9\x00\n", Encoding{Charset: UTF16BE}, "hé\n"},
+		{"\xFF\xFE\x3d\xd8\x00\xde", Encoding{Charset: UTF16LE}, "😀"},
+		{"\x00\x01ab\n", Encoding{Charset: Hex},
+			"00000000: 00 01 61 62 0a                                    |..ab.|\n"},
+		{"0123456789abcdef\x00", Encoding{Charset: Hex},
+			"00000000: 30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  |0123456789abcdef|\n" +
+				"00000010: 00                                                |.|\n"},
+	} {
+		enc := DetectEncoding([]byte(tc.in))
+		if enc != tc.enc {
+			t.Errorf("encoding of %q is %v; expected %v", tc.in, enc, tc.enc)
+		}
+		text := enc.Decode([]byte(tc.in))
+		if string(text) != tc.text {
+			t.Errorf("decoded %q to %q; expected %q", tc.in, text, tc.text)
+		}
+		out, err := enc.Encode(text)
+		if err != nil {
+			t.Errorf("can't encode %q: %v", text, err)
+		}
+		if !bytes.Equal(out, []byte(tc.in)) {
+			t.Errorf("encoded %q to %q; expected %q", text, out, tc.in)
+		}
+	}
+}
+
+func TestUnhexdump(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		err     string
+	}{
+		{"", "", ""},
+		{"00000000: 41 42  |AB|\n", "AB", ""},
+		{"00000000: 41 42 43  |AB|\n", "ABC", ""},            // added a byte
+		{"00000000: 41  |XYZ|\n00000010: 4243\n", "ABC", ""}, // ASCII column ignored
+		{"41 42\n", "AB", ""},
+		{"00000000: 41 4\n", "", `line 1: bad hex "4"`},
+		{"41\n00000010: zz\n", "", `line 2: bad hex "zz"`},
+	} {
+		out, err := unhexdump([]byte(tc.in))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("unhexdump(%q) returned error %v; expected %v", tc.in, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || string(out) != tc.out {
+			t.Errorf("unhexdump(%q) is %q, %v; expected %q", tc.in, out, err, tc.out)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	for _, tc := range []struct {
+		enc Encoding
+		in  string
+		n   int
+	}{
+		{Encoding{}, "", 0},
+		{Encoding{}, "abc", 3},
+		{Encoding{}, "ab\xe4\xb8", 2},
+		{Encoding{}, "ab\xe4\xb8\x96", 5},
+		{Encoding{}, "ab\r", 3},
+		{Encoding{CRLF: true}, "ab\r", 2},
+		{Encoding{CRLF: true}, "ab\r\n", 4},
+		{Encoding{Charset: Latin1}, "ab\xe4", 3},
+		{Encoding{Charset: UTF16LE}, "a\x00b", 2},
+		{Encoding{Charset: UTF16LE}, "a\x00\x3d\xd8", 2},
+		{Encoding{Charset: UTF16BE, CRLF: true}, "\x00a\x00\r", 2},
+		{Encoding{Charset: Hex}, "0123456789abcdefXY", 16},
+	} {
+		if n := tc.enc.Cut([]byte(tc.in)); n != tc.n {
+			t.Errorf("Cut(%q) in %v is %v; expected %v", tc.in, tc.enc, n, tc.n)
+		}
+	}
+}
+
+func TestFits(t *testing.T) {
+	for _, tc := range []struct {
+		enc  Encoding
+		in   string
+		fits bool
+	}{
+		{Encoding{}, "abc\n世界\r\n", true},
+		{Encoding{}, "caf\xe9", false},
+		{Encoding{}, "a\x00b", false},
+		{Encoding{CRLF: true}, "a\r\nb\r\n", true},
+		{Encoding{CRLF: true}, "a\r\nb\n", false},
+		{Encoding{CRLF: true}, "\nb", false},
+		{Encoding{Charset: Latin1}, "caf\xe9", true},
+		{Encoding{Charset: Latin1}, "a\x00b", false},
+		{Encoding{Charset: UTF16LE}, "a\x00\n\x00", true},
+		{Encoding{Charset: UTF16LE}, "a\x00\x00\x00", false},
+		{Encoding{Charset: Hex}, "a\x00b", true},
+	} {
+		if fits := tc.enc.Fits([]byte(tc.in)); fits != tc.fits {
+			t.Errorf("Fits(%q) in %v is %v; expected %v", tc.in, tc.enc, fits, tc.fits)
+		}
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	_, err := Encoding{Charset: Latin1}.Encode([]byte("世界"))
+	want := "can't encode U+4E16 in latin1"
+	if err == nil || err.Error() != want {
+		t.Errorf("Encode returned error %v; expected %v", err, want)
+	}
+}
+
+func TestEncodingParse(t *testing.T) {
+	for _, tc := range []struct {
+		enc  Encoding
+		in   string
+		out  Encoding
+		fail bool
+	}{
+		{Encoding{}, "", Encoding{}, false},
+		{Encoding{}, "crlf", Encoding{CRLF: true}, false},
+		{Encoding{Charset: Latin1, CRLF: true}, "lf", Encoding{Charset: Latin1}, false},
+		{Encoding{CRLF: true}, "UTF-16LE", Encoding{Charset: UTF16LE, CRLF: true}, false},
+		{Encoding{}, "utf-8-bom lf", Encoding{Charset: UTF8BOM}, false},
+		{Encoding{}, "koi8-r", Encoding{}, true},
+	} {
+		out, err := tc.enc.Parse(tc.in)
+		if (err != nil) != tc.fail {
+			t.Errorf("Parse(%q) returned error %v", tc.in, err)
+			continue
+		}
+		if !tc.fail && out != tc.out {
+			t.Errorf("Parse(%q) on %v is %v; expected %v", tc.in, tc.enc, out, tc.out)
+		}
+		if !tc.fail {
+			if s, _ := (Encoding{}).Parse(out.String()); s != out {
+				t.Errorf("%v doesn't parse back: got %v", out, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,540 @@
+package regexp
+
+import (
+	"regexp/syntax"
+	"sync"
+
+	"github.com/fhs/edward/internal/runes"
+)
+
+// The NFA in runes.go and runesb.go keeps a list of threads with their
+// submatch positions and walks it for every rune of the input. When only
+// where a match starts and ends is needed, a lazily built DFA does the same
+// work once per distinct set of threads, after which each rune costs a
+// table lookup. A match is found the way RE2 does it: a forward DFA finds
+// where the leftmost-first match ends, and a DFA running the reversed
+// program backward from there finds where it starts. The NFA is only run
+// from where the match starts, when submatches are wanted.
+//
+// The DFAs see the end of a search as the NFA does: a forward search looks
+// at the runes past it, while a backward search, in match1, takes the text
+// to end there.
+//
+// Empty-width assertions depend on the runes on both sides of a position,
+// so a DFA state holds the threads waiting for the next rune before their
+// empty-width instructions are followed, along with the kind of rune
+// before them. The threads are followed once the next rune is known.
+
+// Kinds of rune that matter to empty-width assertions.
+const (
+	ctxText  = iota // no rune: beginning or end of text
+	ctxNL           // newline
+	ctxWord         // ASCII word character
+	ctxOther        // anything else
+	nctx
+)
+
+// A rune of each kind, for building a lazyFlag.
+var ctxRune = [nctx]rune{endOfText, '\n', 'a', ' '}
+
+func runectx(r rune) uint8 {
+	switch {
+	case r < 0:
+		return ctxText
+	case r == '\n':
+		return ctxNL
+	case syntax.IsWordChar(r):
+		return ctxWord
+	}
+	return ctxOther
+}
+
+// ctxat returns the kind of the rune at r[p], which is ctxText outside r.
+func ctxat(r []rune, p int) uint8 {
+	if uint(p) < uint(len(r)) {
+		return runectx(r[p])
+	}
+	return ctxText
+}
+
+// How a DFA matches.
+type dfamode int
+
+const (
+	// Leftmost-first match starting anywhere, as the NFA does it. The
+	// DFA finds where the match ends.
+	dfaFirst dfamode = iota
+
+	// Leftmost-first match starting where the DFA starts.
+	dfaAnchoredFirst
+
+	// Longest match starting where the DFA starts. Run backward with the
+	// reversed program, it finds where a match ending at the start
+	// position begins.
+	dfaLongest
+
+	// Any match starting anywhere. Run backward with the reversed
+	// program, it finds the positions where matches start.
+	dfaEarliest
+
+	ndfamode
+)
+
+// maxDFAStates limits the size of the cache of DFA states. When it's
+// full, the cache is thrown away and built again; if that happens too
+// often for the DFA to be any faster than the NFA, the search is left to
+// the NFA.
+const maxDFAStates = 1000
+
+// A dstate is a DFA state.
+type dstate struct {
+	insts    []uint32 // threads waiting for the next rune, in priority order
+	ctx      uint8    // kind of the previous rune
+	starting bool     // a new thread is started at each position
+	anymatch bool     // a match can end here, depending on the next rune
+
+	// The threads with their empty-width instructions followed, by the
+	// kind of the next rune, and whether one of them is a match.
+	closure [nctx][]uint32
+	match   [nctx]bool
+
+	// Next states, by rune.
+	ascii [128]*dstate
+	other map[rune]*dstate
+}
+
+// empty reports whether s has no threads and starts none, so no match can
+// be found from it.
+func (s *dstate) empty() bool {
+	return len(s.insts) == 0 && !s.starting
+}
+
+// A dfa is a lazily built DFA for a program. It isn't safe for
+// concurrent use; dfacache hands them out one at a time.
+type dfa struct {
+	prog   *syntax.Prog
+	mode   dfamode
+	states map[string]*dstate
+	key    []byte
+	mark   []uint32 // instructions seen by closure, by generation
+	gen    uint32
+
+	nsteps    int // transitions taken
+	lastreset int // nsteps when the cache was last thrown away
+	failed    bool
+}
+
+func newDFA(prog *syntax.Prog, mode dfamode) *dfa {
+	return &dfa{
+		prog:   prog,
+		mode:   mode,
+		states: make(map[string]*dstate),
+		mark:   make([]uint32, len(prog.Inst)),
+	}
+}
+
+// state returns the state with the given threads.
+func (d *dfa) state(insts []uint32, ctx uint8, starting bool) *dstate {
+	d.key = d.key[:0]
+	for _, pc := range insts {
+		d.key = append(d.key, byte(pc), byte(pc>>8), byte(pc>>16), byte(pc>>24))
+	}
+	d.key = append(d.key, ctx)
+	if starting {
+		d.key = append(d.key, 1)
+	}
+	if s, ok := d.states[string(d.key)]; ok {
+		return s
+	}
+	if len(d.states) >= maxDFAStates {
+		if d.nsteps-d.lastreset < 10*maxDFAStates {
+			d.failed = true
+			return nil
+		}
+		d.lastreset = d.nsteps
+		d.states = make(map[string]*dstate)
+	}
+	s := &dstate{
+		insts:    append([]uint32(nil), insts...),
+		ctx:      ctx,
+		starting: starting,
+	}
+	for next := uint8(0); next < nctx; next++ {
+		d.close(s, next)
+		s.anymatch = s.anymatch || s.match[next]
+	}
+	d.states[string(d.key)] = s
+	return s
+}
+
+// start returns the state at a position after a rune of kind ctx.
+func (d *dfa) start(ctx uint8) *dstate {
+	switch d.mode {
+	case dfaAnchoredFirst, dfaLongest:
+		return d.state([]uint32{uint32(d.prog.Start)}, ctx, false)
+	}
+	return d.state(nil, ctx, true)
+}
+
+// close follows the empty-width instructions of the threads of s when
+// the next rune is of kind next, and records whether a match ends there.
+func (d *dfa) close(s *dstate, next uint8) {
+	d.gen++
+	if d.gen == 0 {
+		for i := range d.mark {
+			d.mark[i] = 0
+		}
+		d.gen = 1
+	}
+	flag := newLazyFlag(ctxRune[s.ctx], ctxRune[next])
+	var list []uint32
+	for _, pc := range s.insts {
+		list = d.add(list, pc, flag)
+	}
+	if s.starting {
+		list = d.add(list, uint32(d.prog.Start), flag)
+	}
+	for j, pc := range list {
+		if d.prog.Inst[pc].Op == syntax.InstMatch {
+			s.match[next] = true
+			if d.mode == dfaFirst || d.mode == dfaAnchoredFirst {
+				// Lower priority threads can't win.
+				list = list[:j]
+				break
+			}
+		}
+	}
+	s.closure[next] = list
+}
+
+// add appends the threads reachable from pc by following empty-width
+// instructions allowed by flag, in priority order, as machine.add does.
+func (d *dfa) add(list []uint32, pc uint32, flag lazyFlag) []uint32 {
+	if pc == 0 || d.mark[pc] == d.gen {
+		return list
+	}
+	d.mark[pc] = d.gen
+	i := &d.prog.Inst[pc]
+	switch i.Op {
+	case syntax.InstFail:
+	case syntax.InstAlt, syntax.InstAltMatch:
+		list = d.add(list, i.Out, flag)
+		list = d.add(list, i.Arg, flag)
+	case syntax.InstEmptyWidth:
+		if flag.match(syntax.EmptyOp(i.Arg)) {
+			list = d.add(list, i.Out, flag)
+		}
+	case syntax.InstNop, syntax.InstCapture:
+		list = d.add(list, i.Out, flag)
+	default:
+		list = append(list, pc)
+	}
+	return list
+}
+
+// matchat reports whether a match ends where the DFA is in state s, when
+// the next rune is of kind ctx.
+func matchat(s *dstate, ctx uint8) bool {
+	return s.anymatch && s.match[ctx]
+}
+
+// next returns the state after s when c is the next rune. It returns nil
+// if the DFA has failed.
+func (d *dfa) next(s *dstate, c rune) *dstate {
+	d.nsteps++
+	if 0 <= c && c < 128 {
+		if ns := s.ascii[c]; ns != nil {
+			return ns
+		}
+	} else if ns, ok := s.other[c]; ok {
+		return ns
+	}
+	ctx := runectx(c)
+	var insts []uint32
+	for _, pc := range s.closure[ctx] {
+		i := &d.prog.Inst[pc]
+		var ok bool
+		switch i.Op {
+		case syntax.InstRune:
+			ok = i.MatchRune(c)
+		case syntax.InstRune1:
+			ok = c == i.Rune[0]
+		case syntax.InstRuneAny:
+			ok = true
+		case syntax.InstRuneAnyNotNL:
+			ok = c != '\n'
+		}
+		if ok && !containsInst(insts, i.Out) {
+			insts = append(insts, i.Out)
+		}
+	}
+	starting := s.starting
+	if d.mode == dfaFirst && s.match[ctx] {
+		starting = false // the leftmost match has been found
+	}
+	ns := d.state(insts, ctx, starting)
+	if ns == nil {
+		return nil
+	}
+	if 0 <= c && c < 128 {
+		s.ascii[c] = ns
+	} else {
+		if s.other == nil {
+			s.other = make(map[rune]*dstate)
+		}
+		s.other[c] = ns
+	}
+	return ns
+}
+
+func containsInst(insts []uint32, pc uint32) bool {
+	for _, p := range insts {
+		if p == pc {
+			return true
+		}
+	}
+	return false
+}
+
+// forward runs the DFA over r[pos:end] and returns where the match ends.
+// In dfaFirst mode, prefix is the literal every match starts with, if
+// there is one. The runes outside r[pos:end] are only looked at by
+// empty-width assertions, and not at all after end if cut is set, in
+// which case the text is taken to end there.
+func (d *dfa) forward(r []rune, pos, end int, prefix []rune, cut bool) (e int, found bool) {
+	s := d.start(ctxat(r, pos-1))
+	if s == nil {
+		return 0, false
+	}
+	for p := pos; ; p++ {
+		if len(prefix) > 0 && len(s.insts) == 0 && s.starting {
+			// No thread is running, so the match starts with prefix.
+			q := indexRunes(r[p:end], prefix)
+			if q < 0 {
+				return 0, false
+			}
+			if q > 0 {
+				p += q
+				if s = d.start(ctxat(r, p-1)); s == nil {
+					return 0, false
+				}
+			}
+		}
+		ctx := ctxat(r, p)
+		if cut && p == end {
+			ctx = ctxText
+		}
+		if matchat(s, ctx) {
+			e, found = p, true
+		}
+		if p >= end {
+			break
+		}
+		c := r[p]
+		if 0 <= c && c < 128 && s.ascii[c] != nil {
+			d.nsteps++
+			s = s.ascii[c]
+		} else if s = d.next(s, c); s == nil {
+			return 0, false
+		}
+		if s.empty() {
+			break
+		}
+	}
+	return e, found
+}
+
+// backward runs a dfaLongest DFA, for the reversed program, backward over
+// r[lo:pos] and returns where the leftmost match ending at pos starts.
+func (d *dfa) backward(r []rune, lo, pos int) (s int, found bool) {
+	st := d.start(ctxat(r, pos))
+	if st == nil {
+		return 0, false
+	}
+	for p := pos; ; p-- {
+		if matchat(st, ctxat(r, p-1)) {
+			s, found = p, true
+		}
+		if p <= lo {
+			break
+		}
+		c := r[p-1]
+		if 0 <= c && c < 128 && st.ascii[c] != nil {
+			d.nsteps++
+			st = st.ascii[c]
+		} else if st = d.next(st, c); st == nil {
+			return 0, false
+		}
+		if st.empty() {
+			break
+		}
+	}
+	return s, found
+}
+
+// A backscan finds, from the end of r[lo:hi] to its start, the positions
+// where matches within r[lo:hi] start, using a dfaEarliest DFA. The text
+// is taken to end at hi, as match1 takes it to for a match that ends
+// there after some runes.
+type backscan struct {
+	d  *dfa
+	r  []rune
+	lo int
+	p  int     // position of st
+	st *dstate // state after scanning r[p:hi]
+}
+
+func (d *dfa) newbackscan(r []rune, lo, hi int) *backscan {
+	return &backscan{
+		d:  d,
+		r:  r,
+		lo: lo,
+		p:  hi,
+		st: d.start(ctxText),
+	}
+}
+
+// prev returns the last position at or before pos where a match starts,
+// or -1 if there's none. The positions asked about must decrease. It
+// reports false if the DFA has failed.
+func (b *backscan) prev(pos int) (int, bool) {
+	d, r, p, st := b.d, b.r, b.p, b.st
+	defer func() { b.p, b.st = p, st }()
+	for st != nil {
+		if p <= pos && matchat(st, ctxat(r, p-1)) {
+			return p, true
+		}
+		if p <= b.lo {
+			return -1, true
+		}
+		c := r[p-1]
+		if 0 <= c && c < 128 && st.ascii[c] != nil {
+			d.nsteps++
+			st = st.ascii[c]
+		} else {
+			st = d.next(st, c)
+		}
+		p--
+	}
+	return -1, false
+}
+
+// A dfacache holds the DFAs of a Regexp, for each mode, ready for use.
+type dfacache struct {
+	expr  string
+	flags syntax.Flags
+	prog  *syntax.Prog
+
+	once  sync.Once
+	rprog *syntax.Prog // reversed program; nil if it can't be made
+
+	mu   sync.Mutex
+	free [ndfamode][]*dfa
+}
+
+func newDFACache(expr string, flags syntax.Flags, prog *syntax.Prog) *dfacache {
+	return &dfacache{
+		expr:  expr,
+		flags: flags,
+		prog:  prog,
+	}
+}
+
+// get returns a DFA for mode, or nil if there can't be one.
+func (c *dfacache) get(mode dfamode) *dfa {
+	c.mu.Lock()
+	if n := len(c.free[mode]); n > 0 {
+		d := c.free[mode][n-1]
+		c.free[mode] = c.free[mode][:n-1]
+		c.mu.Unlock()
+		d.failed = false
+		return d
+	}
+	c.mu.Unlock()
+
+	prog := c.prog
+	if mode == dfaLongest || mode == dfaEarliest {
+		c.once.Do(c.reverse)
+		if c.rprog == nil {
+			return nil
+		}
+		prog = c.rprog
+	}
+	return newDFA(prog, mode)
+}
+
+// put returns d to the cache, unless it has failed, in which case it's
+// likely to fail again.
+func (c *dfacache) put(d *dfa) {
+	if d == nil || d.failed {
+		return
+	}
+	c.mu.Lock()
+	c.free[d.mode] = append(c.free[d.mode], d)
+	c.mu.Unlock()
+}
+
+// reverse compiles the reversed program.
+func (c *dfacache) reverse() {
+	re, err := syntax.Parse(c.expr, c.flags)
+	if err != nil {
+		return
+	}
+	prog, err := syntax.Compile(reverseSyntax(re).Simplify())
+	if err != nil {
+		return
+	}
+	c.rprog = prog
+}
+
+// reverseSyntax returns a regexp matching the reverse of the strings
+// matched by re, with the meanings of the empty-width assertions, which
+// look at the runes on either side, swapped to suit.
+func reverseSyntax(re *syntax.Regexp) *syntax.Regexp {
+	rev := *re
+	rev.Sub = nil
+	for _, sub := range re.Sub {
+		rev.Sub = append(rev.Sub, reverseSyntax(sub))
+	}
+	switch re.Op {
+	case syntax.OpConcat:
+		for i, j := 0, len(rev.Sub)-1; i < j; i, j = i+1, j-1 {
+			rev.Sub[i], rev.Sub[j] = rev.Sub[j], rev.Sub[i]
+		}
+	case syntax.OpLiteral:
+		rev.Rune = make([]rune, len(re.Rune))
+		for i, r := range re.Rune {
+			rev.Rune[len(re.Rune)-1-i] = r
+		}
+	case syntax.OpBeginLine:
+		rev.Op = syntax.OpEndLine
+	case syntax.OpEndLine:
+		rev.Op = syntax.OpBeginLine
+	case syntax.OpBeginText:
+		rev.Op = syntax.OpEndText
+	case syntax.OpEndText:
+		rev.Op = syntax.OpBeginText
+	}
+	return &rev
+}
+
+// indexRunes is like runes.Index, looking for the first rune of sep
+// before comparing the rest.
+func indexRunes(s, sep []rune) int {
+	n := len(sep)
+	if n == 0 {
+		return 0
+	}
+	last := len(s) - n
+	for i := 0; i <= last; i++ {
+		j := runes.IndexRune(s[i:last+1], sep[0])
+		if j < 0 {
+			return -1
+		}
+		i += j
+		if runes.Equal(s[i+1:i+n], sep[1:]) {
+			return i
+		}
+	}
+	return -1
+}
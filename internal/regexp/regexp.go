@@ -88,11 +88,13 @@ type Regexp struct {
 	prefix         string         // required prefix in unanchored matches
 	prefixBytes    []byte         // prefix, as a []byte
 	prefixRune     rune           // first rune in prefix
+	prefixRunes    []rune         // prefix, as a []rune
 	prefixEnd      uint32         // pc for last rune in prefix
 	mpool          int            // pool for machines
 	matchcap       int            // size of recorded match lengths
 	prefixComplete bool           // prefix is the entire regexp
 	cond           syntax.EmptyOp // empty-width conditions required at start of match
+	dfas           *dfacache      // DFAs for searching runes; nil if they can't be used
 
 	// This field can be modified by the Longest method,
 	// but it is otherwise read-only.
@@ -202,6 +204,10 @@ func compile(expr string, mode syntax.Flags, longest bool) (*Regexp, error) {
 		// IndexString to package bytes.
 		regexp.prefixBytes = []byte(regexp.prefix)
 		regexp.prefixRune, _ = utf8.DecodeRuneInString(regexp.prefix)
+		regexp.prefixRunes = []rune(regexp.prefix)
+	}
+	if !longest {
+		regexp.dfas = newDFACache(expr, mode, prog)
 	}
 
 	n := len(prog.Inst)
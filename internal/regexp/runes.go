@@ -44,7 +44,7 @@ func (re *Regexp) allMatchesRunes(r []rune, start int, end int, n int, deliver f
 		end:   end,
 	}
 	for pos, i, prevMatchEnd := start, 0, -1; i < n && pos <= end; {
-		matches := re.doExecuteInput(ri, pos, re.prog.NumCap, nil)
+		matches := re.doExecuteRunes(ri, pos)
 		if len(matches) == 0 {
 			break
 		}
@@ -71,6 +71,38 @@ func (re *Regexp) allMatchesRunes(r []rune, start int, end int, n int, deliver f
 	}
 }
 
+// doExecuteRunes finds the leftmost match in the runes starting at or
+// after pos. The DFAs find where it is, if they can, leaving the NFA to
+// find the submatches, if any, starting from it. An empty search is left
+// to the NFA, which takes the text to end there if it's at the start.
+func (re *Regexp) doExecuteRunes(ri *inputRunes, pos int) []int {
+	if re.dfas == nil || re.longest || pos == ri.end {
+		return re.doExecuteInput(ri, pos, re.prog.NumCap, nil)
+	}
+	fwd := re.dfas.get(dfaFirst)
+	e, found := fwd.forward(ri.str, pos, ri.end, re.prefixRunes, false)
+	re.dfas.put(fwd)
+	if fwd.failed {
+		return re.doExecuteInput(ri, pos, re.prog.NumCap, nil)
+	}
+	if !found {
+		return nil
+	}
+	rev := re.dfas.get(dfaLongest)
+	if rev == nil {
+		return re.doExecuteInput(ri, pos, re.prog.NumCap, nil)
+	}
+	s, found := rev.backward(ri.str, pos, e)
+	re.dfas.put(rev)
+	if rev.failed || !found {
+		return re.doExecuteInput(ri, pos, re.prog.NumCap, nil)
+	}
+	if re.prog.NumCap <= 2 {
+		return []int{s, e}
+	}
+	return re.doExecuteInput(ri, s, re.prog.NumCap, nil)
+}
+
 // doExecuteInput finds the leftmost match in the input, appends the position
 // of its subexpressions to dstCap and returns dstCap.
 //
@@ -189,11 +221,11 @@ func (i *inputRunes) canCheckPrefix() bool {
 }
 
 func (i *inputRunes) hasPrefix(re *Regexp) bool {
-	return runes.HasPrefix(i.str[i.start:i.end], []rune(re.prefix))
+	return runes.HasPrefix(i.str[i.start:i.end], re.prefixRunes)
 }
 
 func (i *inputRunes) index(re *Regexp, pos int) int {
-	return runes.Index(i.str[pos:i.end], []rune(re.prefix))
+	return indexRunes(i.str[pos:i.end], re.prefixRunes)
 }
 
 func (i *inputRunes) context(pos int) lazyFlag {
@@ -3,6 +3,8 @@ package regexp
 import (
 	"fmt"
 	"reflect"
+	"regexp/syntax"
+	"sync"
 	"testing"
 )
 
@@ -93,3 +95,224 @@ func reverseMatches(m [][]int) [][]int {
 	}
 	return b
 }
+
+// TestRegexpSearchEnd pins down how empty-width assertions see the end of
+// a search that stops before the end of the text. A forward search looks
+// past it, but a backward search takes the text to end there, except for
+// an empty match right at it. The DFAs must agree.
+func TestRegexpSearchEnd(t *testing.T) {
+	for _, tc := range []struct {
+		text       string
+		start, end int
+		re         string
+		fwd, bwd   [][]int
+	}{
+		{"abc", 0, 2, "b$", nil, [][]int{{1, 2}}},
+		{"ab\ncd", 0, 2, "b$", [][]int{{1, 2}}, [][]int{{1, 2}}},
+		{"abc", 0, 2, "$", nil, nil},
+		{"abc", 2, 2, "$", nil, nil},
+		{"abc", 0, 0, "\\z", [][]int{{0, 0}}, [][]int{{0, 0}}},
+		{"abc", 0, 2, "b\\z", nil, [][]int{{1, 2}}},
+		{"ab cd", 0, 2, "\\b", [][]int{{0, 0}, {2, 2}}, [][]int{{2, 2}, {0, 0}}},
+		{"ab cd", 0, 2, "b\\b", [][]int{{1, 2}}, [][]int{{1, 2}}},
+		{"abcd", 0, 2, "\\B", [][]int{{1, 1}, {2, 2}}, [][]int{{2, 2}, {1, 1}}},
+		{"abc", 0, 2, "(ab$|a)", [][]int{{0, 1, 0, 1}}, [][]int{{0, 2, 0, 2}}},
+	} {
+		re, err := CompileAcme(tc.re)
+		if err != nil {
+			t.Fatalf("failed to compile regular expression %q", tc.re)
+		}
+		nfa := *re
+		nfa.dfas = nil
+		r := []rune(tc.text)
+		for _, x := range []*Regexp{re, &nfa} {
+			if got := x.FindForward(r, tc.start, tc.end, -1); !reflect.DeepEqual(got, tc.fwd) {
+				t.Errorf("FindForward(%q, %q, %d, %d) with DFAs %v is %v; want %v",
+					tc.re, tc.text, tc.start, tc.end, x.dfas != nil, got, tc.fwd)
+			}
+			if got := x.FindBackward(r, tc.start, tc.end, -1); !reflect.DeepEqual(got, tc.bwd) {
+				t.Errorf("FindBackward(%q, %q, %d, %d) with DFAs %v is %v; want %v",
+					tc.re, tc.text, tc.start, tc.end, x.dfas != nil, got, tc.bwd)
+			}
+		}
+	}
+}
+
+// dfaTexts and dfaPatterns are searched with and without the DFAs, which
+// must find the same matches.
+var dfaTexts = []string{
+	"",
+	"a",
+	"abc abc\nabd\n\nxabcx abc",
+	"foo bar\nbaz foo\nfoofoo\n  foo_bar foo.bar\n",
+	"aaaa\nbbbb\naabb\n\n\nab",
+	"<a><b></b></a>\n<c>",
+	"α 世界 α\nβ 世界\n世界",
+	"x=1; y = 22;\nz=333\n",
+}
+
+var dfaPatterns = []string{
+	"a",
+	"abc",
+	"foo",
+	"a+",
+	"a*",
+	"a*?",
+	"ab|a",
+	"a|ab",
+	"(a|ab)(c|bcd)",
+	".*",
+	".+",
+	".*?b",
+	"^",
+	"$",
+	"^$",
+	"^a",
+	"b$",
+	"^.*$",
+	"\\bfoo\\b",
+	"\\Bo",
+	"\\b",
+	"\\B",
+	"(?i)ABC",
+	"(?s).+",
+	"[a-c]+",
+	"[^a\\n]+",
+	"<.*?>",
+	"<.*>",
+	"世界",
+	"[α-ω]",
+	"\\d+",
+	"(\\w+)=(\\d+)",
+	"(?P<key>\\w+) *= *(?P<val>\\d+)",
+	"x*",
+	"(a*)*",
+	"(a|b)*b",
+	"\\Aa",
+	"a\\z",
+	"foo|bar|baz",
+	"o\\n",
+	"\\n\\n",
+	"(?m)^b",
+	"(ab$|a)",
+	"b\\z",
+	"b\\B",
+}
+
+func TestDFAMatchesNFA(t *testing.T) {
+	for _, pat := range dfaPatterns {
+		re, err := CompileAcme(pat)
+		if err != nil {
+			t.Fatalf("failed to compile regular expression %q", pat)
+		}
+		nfa := *re
+		nfa.dfas = nil
+		for _, text := range dfaTexts {
+			r := []rune(text)
+			for start := 0; start <= len(r); start++ {
+				for end := start; end <= len(r); end++ {
+					want := nfa.FindForward(r, start, end, -1)
+					got := re.FindForward(r, start, end, -1)
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("FindForward(%q, %q, %d, %d) is %v; want %v", pat, text, start, end, got, want)
+					}
+					want = nfa.FindBackward(r, start, end, -1)
+					got = re.FindBackward(r, start, end, -1)
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("FindBackward(%q, %q, %d, %d) is %v; want %v", pat, text, start, end, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestReverseSyntax(t *testing.T) {
+	for _, tc := range []struct {
+		re, rev string
+	}{
+		{"abc", "cba"},
+		{"a(bc|de)f", "f(cb|ed)a"},
+		{"(?m)^ab$", "(?m:^ba$)"}, // still at the start and end of a line
+		{"\\Ab\\z", "\\Ab\\z"},
+		{"\\Aab", "ba\\z"},
+		{"\\bx+\\B", "\\Bx+\\b"},
+	} {
+		re, err := syntax.Parse(tc.re, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := reverseSyntax(re).String(); got != tc.rev {
+			t.Errorf("reverse of %q is %q; want %q", tc.re, got, tc.rev)
+		}
+	}
+}
+
+// benchText is about 100 MB of text, with a line holding foo once every
+// megabyte or so.
+var (
+	benchTextOnce sync.Once
+	benchText     []rune
+)
+
+func getBenchText() []rune {
+	benchTextOnce.Do(func() {
+		const size = 100 << 20
+		line := []rune("\tif err := w.body.Load(0, name, true); err != nil { return err }\n")
+		benchText = make([]rune, 0, size+len(line))
+		for n := 0; len(benchText) < size; n++ {
+			if n%(1<<14) == 0 {
+				benchText = append(benchText, []rune("\tfoo(bar)\n")...)
+			}
+			benchText = append(benchText, line...)
+		}
+	})
+	return benchText
+}
+
+func BenchmarkFindForward(b *testing.B) {
+	benchmarkFind(b, getBenchText(), func(re *Regexp, r []rune) int {
+		return len(re.FindForward(r, 0, len(r), -1))
+	})
+}
+
+// BenchmarkFindBackward searches only the first megabyte of the text,
+// since without a DFA the search tries a match at every position.
+func BenchmarkFindBackward(b *testing.B) {
+	benchmarkFind(b, getBenchText()[:1<<20], func(re *Regexp, r []rune) int {
+		return len(re.FindBackward(r, 0, len(r), 1))
+	})
+}
+
+func benchmarkFind(b *testing.B, r []rune, find func(re *Regexp, r []rune) int) {
+	for _, pat := range []string{
+		"foo",               // literal prefix
+		"f[aeiou]+\\(",      // no literal prefix
+		"(?i)FOO\\(",        // ignoring case
+		"^\\tfoo.*$",        // anchors
+		"[a-z]+\\(bar\\)",   // starts at every word
+		"(x+x+)+y",          // pathological for backtracking
+		"(?P<f>\\w+)\\(\\)", // submatches, found by the NFA within the match
+	} {
+		re, err := CompileAcme(pat)
+		if err != nil {
+			b.Fatal(err)
+		}
+		nfa := *re
+		nfa.dfas = nil
+		for _, m := range []struct {
+			name string
+			re   *Regexp
+		}{
+			{"nfa", &nfa},
+			{"dfa", re},
+		} {
+			b.Run(fmt.Sprintf("%s/%s", pat, m.name), func(b *testing.B) {
+				b.SetBytes(int64(len(r)))
+				for i := 0; i < b.N; i++ {
+					find(m.re, r)
+				}
+			})
+		}
+	}
+}
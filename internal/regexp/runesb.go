@@ -56,8 +56,25 @@ func (re *Regexp) allMatchesRunesBackward(r []rune, start int, end int, n int, d
 		start: start,
 		end:   end,
 	}
+	var scan *backscan
+	if re.dfas != nil && !re.longest {
+		if d := re.dfas.get(dfaEarliest); d != nil {
+			scan = d.newbackscan(r, start, end)
+			defer re.dfas.put(d)
+		}
+	}
 	for pos, i, prevMatchStart := end, 0, -1; i < n && pos >= start; {
-		matches := re.doExecuteInput1(ri, pos, re.prog.NumCap, nil)
+		if scan != nil && pos < end {
+			// Skip to where the next match starts.
+			if p, ok := scan.prev(pos); !ok {
+				scan = nil
+			} else if p < 0 {
+				break
+			} else {
+				pos = p
+			}
+		}
+		matches := re.doExecuteRunes1(ri, pos)
 		if len(matches) == 0 {
 			pos--
 			continue
@@ -82,6 +99,27 @@ func (re *Regexp) allMatchesRunesBackward(r []rune, start int, end int, n int, d
 	}
 }
 
+// doExecuteRunes1 finds the match in the runes that begins at pos, like
+// doExecuteInput1, using a DFA to find where it ends if there are no
+// submatches to find. An empty match at the end of the search is left to
+// the NFA: match1 looks past the end of the search for it, but not for a
+// match ending there after some runes.
+func (re *Regexp) doExecuteRunes1(ri *inputRunes, pos int) []int {
+	if re.dfas == nil || re.longest || re.prog.NumCap > 2 || pos == ri.end {
+		return re.doExecuteInput1(ri, pos, re.prog.NumCap, nil)
+	}
+	d := re.dfas.get(dfaAnchoredFirst)
+	e, found := d.forward(ri.str, pos, ri.end, nil, true)
+	re.dfas.put(d)
+	if d.failed {
+		return re.doExecuteInput1(ri, pos, re.prog.NumCap, nil)
+	}
+	if !found {
+		return nil
+	}
+	return []int{pos, e}
+}
+
 // doExecuteInput1 finds the match in the input that begins at pos (if there is one),
 // and appends the position of its subexpressions to dstCap and returns dstCap.
 //
@@ -0,0 +1,324 @@
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var builtins = map[string]Func{
+	"+":              arith("+", func(a, b int) (int, error) { return a + b, nil }),
+	"-":              arith("-", func(a, b int) (int, error) { return a - b, nil }),
+	"*":              arith("*", func(a, b int) (int, error) { return a * b, nil }),
+	"/":              arith("/", divide),
+	"%":              arith("%", modulo),
+	"=":              compare("=", func(a, b int) bool { return a == b }),
+	"<":              compare("<", func(a, b int) bool { return a < b }),
+	">":              compare(">", func(a, b int) bool { return a > b }),
+	"<=":             compare("<=", func(a, b int) bool { return a <= b }),
+	">=":             compare(">=", func(a, b int) bool { return a >= b }),
+	"equal?":         equal,
+	"not":            not,
+	"string-append":  stringAppend,
+	"string-length":  stringLength,
+	"substring":      substring,
+	"string-index":   stringIndex,
+	"number->string": numberToString,
+	"string->number": stringToNumber,
+	"list":           list,
+	"cons":           cons,
+	"car":            car,
+	"cdr":            cdr,
+	"null?":          null,
+	"length":         length,
+	"error":          raise,
+}
+
+// CheckArgs returns an error unless there are n args. Functions given to
+// Interp.Define may use it and the other argument helpers.
+func CheckArgs(name string, args []Value, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%v: %d arguments; expected %d", name, len(args), n)
+	}
+	return nil
+}
+
+// Int returns args[i] as an integer.
+func Int(name string, args []Value, i int) (int, error) {
+	n, ok := args[i].(int)
+	if !ok {
+		return 0, fmt.Errorf("%v: argument %d is %v; expected a number", name, i+1, Format(args[i]))
+	}
+	return n, nil
+}
+
+// String returns args[i] as a string.
+func String(name string, args []Value, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("%v: argument %d is %v; expected a string", name, i+1, Format(args[i]))
+	}
+	return s, nil
+}
+
+func arith(name string, op func(a, b int) (int, error)) Func {
+	return func(args []Value) (Value, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%v: no arguments", name)
+		}
+		v, err := Int(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 1 && name == "-" {
+			return -v, nil
+		}
+		for i := 1; i < len(args); i++ {
+			n, err := Int(name, args, i)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = op(v, n); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+}
+
+func divide(a, b int) (int, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("/: division by zero")
+	}
+	return a / b, nil
+}
+
+func modulo(a, b int) (int, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("%%: division by zero")
+	}
+	return a % b, nil
+}
+
+func compare(name string, op func(a, b int) bool) Func {
+	return func(args []Value) (Value, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%v: %d arguments; expected at least 2", name, len(args))
+		}
+		for i := 0; i+1 < len(args); i++ {
+			a, err := Int(name, args, i)
+			if err != nil {
+				return nil, err
+			}
+			b, err := Int(name, args, i+1)
+			if err != nil {
+				return nil, err
+			}
+			if !op(a, b) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func equal(args []Value) (Value, error) {
+	if err := CheckArgs("equal?", args, 2); err != nil {
+		return nil, err
+	}
+	a, b := args[0], args[1]
+	if la, ok := a.([]Value); ok && len(la) == 0 {
+		a = nil
+	}
+	if lb, ok := b.([]Value); ok && len(lb) == 0 {
+		b = nil
+	}
+	switch a.(type) {
+	case *Lambda, Func:
+		return false, nil
+	}
+	return reflect.DeepEqual(a, b), nil
+}
+
+func not(args []Value) (Value, error) {
+	if err := CheckArgs("not", args, 1); err != nil {
+		return nil, err
+	}
+	return !Truth(args[0]), nil
+}
+
+func stringAppend(args []Value) (Value, error) {
+	var b strings.Builder
+	for i := range args {
+		s, err := String("string-append", args, i)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+func stringLength(args []Value) (Value, error) {
+	if err := CheckArgs("string-length", args, 1); err != nil {
+		return nil, err
+	}
+	s, err := String("string-length", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return len([]rune(s)), nil
+}
+
+func substring(args []Value) (Value, error) {
+	if err := CheckArgs("substring", args, 3); err != nil {
+		return nil, err
+	}
+	s, err := String("substring", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	i, err := Int("substring", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	j, err := Int("substring", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	r := []rune(s)
+	if i < 0 || j < i || j > len(r) {
+		return nil, fmt.Errorf("substring: range %d,%d out of bounds", i, j)
+	}
+	return string(r[i:j]), nil
+}
+
+// stringIndex returns the rune offset of the first instance of a substring, or -1.
+func stringIndex(args []Value) (Value, error) {
+	if err := CheckArgs("string-index", args, 2); err != nil {
+		return nil, err
+	}
+	s, err := String("string-index", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := String("string-index", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return -1, nil
+	}
+	return len([]rune(s[:i])), nil
+}
+
+func numberToString(args []Value) (Value, error) {
+	if err := CheckArgs("number->string", args, 1); err != nil {
+		return nil, err
+	}
+	n, err := Int("number->string", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strconv.Itoa(n), nil
+}
+
+// stringToNumber returns the number in a string, or #f if there isn't one.
+func stringToNumber(args []Value) (Value, error) {
+	if err := CheckArgs("string->number", args, 1); err != nil {
+		return nil, err
+	}
+	s, err := String("string->number", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return false, nil
+	}
+	return n, nil
+}
+
+func list(args []Value) (Value, error) {
+	return append([]Value(nil), args...), nil
+}
+
+func cons(args []Value) (Value, error) {
+	if err := CheckArgs("cons", args, 2); err != nil {
+		return nil, err
+	}
+	switch l := args[1].(type) {
+	case nil:
+		return []Value{args[0]}, nil
+	case []Value:
+		return append([]Value{args[0]}, l...), nil
+	}
+	return nil, fmt.Errorf("cons: %v is not a list", Format(args[1]))
+}
+
+func listArg(name string, args []Value) ([]Value, error) {
+	if err := CheckArgs(name, args, 1); err != nil {
+		return nil, err
+	}
+	switch l := args[0].(type) {
+	case nil:
+		return nil, nil
+	case []Value:
+		return l, nil
+	}
+	return nil, fmt.Errorf("%v: %v is not a list", name, Format(args[0]))
+}
+
+func car(args []Value) (Value, error) {
+	l, err := listArg("car", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(l) == 0 {
+		return nil, fmt.Errorf("car: empty list")
+	}
+	return l[0], nil
+}
+
+func cdr(args []Value) (Value, error) {
+	l, err := listArg("cdr", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(l) == 0 {
+		return nil, fmt.Errorf("cdr: empty list")
+	}
+	return l[1:], nil
+}
+
+func null(args []Value) (Value, error) {
+	if err := CheckArgs("null?", args, 1); err != nil {
+		return nil, err
+	}
+	switch l := args[0].(type) {
+	case nil:
+		return true, nil
+	case []Value:
+		return len(l) == 0, nil
+	}
+	return false, nil
+}
+
+func length(args []Value) (Value, error) {
+	l, err := listArg("length", args)
+	if err != nil {
+		return nil, err
+	}
+	return len(l), nil
+}
+
+// raise returns its arguments, printed and separated by spaces, as an error.
+func raise(args []Value) (Value, error) {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i] = Format(a)
+	}
+	return nil, fmt.Errorf("%s", strings.Join(s, " "))
+}
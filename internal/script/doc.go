@@ -0,0 +1,26 @@
+// Package script implements a small Scheme-like language used to extend
+// Edward with new commands without starting an external process.
+//
+// A script is a sequence of expressions. The values are integers, strings,
+// booleans (#t and #f), symbols, lists and functions. Only #f and the empty
+// list are false. The special forms are
+//
+//	(quote x) or 'x
+//	(if cond then [else])
+//	(cond (test expr...)... [(else expr...)])
+//	(define name value) or (define (name params...) body...)
+//	(set! name value)
+//	(lambda (params...) body...)
+//	(let ((name value)...) body...)
+//	(begin expr...)
+//	(while cond body...)
+//	(and expr...) and (or expr...)
+//
+// The built-in functions cover arithmetic (+ - * / %), comparison
+// (= < > <= >=, equal?, not), strings (string-append, string-length,
+// substring, string-index, number->string, string->number) and lists
+// (list, cons, car, cdr, null?, length). (error args...) stops the script
+// with an error. Strings are indexed by rune. A script that evaluates too
+// many expressions, as in an endless loop, is stopped with an error.
+// Hosts add their own functions with Define.
+package script
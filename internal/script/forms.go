@@ -0,0 +1,224 @@
+package script
+
+import "fmt"
+
+type specialForm func(in *Interp, args []Value, e *env) (Value, error)
+
+var specialForms map[Symbol]specialForm
+
+func init() {
+	// We need this in init() to avoid initialization loop.
+	specialForms = map[Symbol]specialForm{
+		"quote":  formQuote,
+		"if":     formIf,
+		"cond":   formCond,
+		"define": formDefine,
+		"set!":   formSet,
+		"lambda": formLambda,
+		"let":    formLet,
+		"begin":  formBegin,
+		"while":  formWhile,
+		"and":    formAnd,
+		"or":     formOr,
+	}
+}
+
+func formQuote(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("quote: bad syntax")
+	}
+	return args[0], nil
+}
+
+func formIf(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("if: bad syntax")
+	}
+	c, err := in.eval(args[0], e)
+	if err != nil {
+		return nil, err
+	}
+	if Truth(c) {
+		return in.eval(args[1], e)
+	}
+	if len(args) == 3 {
+		return in.eval(args[2], e)
+	}
+	return nil, nil
+}
+
+func formCond(in *Interp, args []Value, e *env) (Value, error) {
+	for _, a := range args {
+		clause, ok := a.([]Value)
+		if !ok || len(clause) == 0 {
+			return nil, fmt.Errorf("cond: bad clause %v", Format(a))
+		}
+		if clause[0] != Symbol("else") {
+			c, err := in.eval(clause[0], e)
+			if err != nil {
+				return nil, err
+			}
+			if !Truth(c) {
+				continue
+			}
+		}
+		return in.evalBody(clause[1:], e)
+	}
+	return nil, nil
+}
+
+func formDefine(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("define: bad syntax")
+	}
+	switch target := args[0].(type) {
+	case Symbol:
+		if len(args) != 2 {
+			return nil, fmt.Errorf("define: bad syntax")
+		}
+		v, err := in.eval(args[1], e)
+		if err != nil {
+			return nil, err
+		}
+		if fn, ok := v.(*Lambda); ok && fn.name == "" {
+			fn.name = string(target)
+		}
+		e.vars[target] = v
+		return nil, nil
+	case []Value:
+		// (define (name params...) body...)
+		if len(target) == 0 {
+			return nil, fmt.Errorf("define: bad syntax")
+		}
+		name, ok := target[0].(Symbol)
+		if !ok {
+			return nil, fmt.Errorf("define: bad function name %v", Format(target[0]))
+		}
+		fn, err := newLambda(string(name), target[1:], args[1:], e)
+		if err != nil {
+			return nil, err
+		}
+		e.vars[name] = fn
+		return nil, nil
+	}
+	return nil, fmt.Errorf("define: bad syntax")
+}
+
+func formSet(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("set!: bad syntax")
+	}
+	s, ok := args[0].(Symbol)
+	if !ok {
+		return nil, fmt.Errorf("set!: bad variable %v", Format(args[0]))
+	}
+	d, ok := e.lookup(s)
+	if !ok {
+		return nil, fmt.Errorf("set!: undefined: %v", s)
+	}
+	v, err := in.eval(args[1], e)
+	if err != nil {
+		return nil, err
+	}
+	d.vars[s] = v
+	return nil, nil
+}
+
+func formLambda(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("lambda: bad syntax")
+	}
+	params, ok := args[0].([]Value)
+	if !ok && args[0] != nil {
+		return nil, fmt.Errorf("lambda: bad parameter list")
+	}
+	return newLambda("", params, args[1:], e)
+}
+
+func newLambda(name string, params []Value, body []Value, e *env) (*Lambda, error) {
+	fn := &Lambda{name: name, body: body, env: e}
+	for _, p := range params {
+		s, ok := p.(Symbol)
+		if !ok {
+			return nil, fmt.Errorf("bad parameter %v", Format(p))
+		}
+		fn.params = append(fn.params, s)
+	}
+	return fn, nil
+}
+
+func formLet(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("let: bad syntax")
+	}
+	bindings, ok := args[0].([]Value)
+	if !ok && args[0] != nil {
+		return nil, fmt.Errorf("let: bad bindings")
+	}
+	le := newEnv(e)
+	for _, b := range bindings {
+		pair, ok := b.([]Value)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("let: bad binding %v", Format(b))
+		}
+		s, ok := pair[0].(Symbol)
+		if !ok {
+			return nil, fmt.Errorf("let: bad variable %v", Format(pair[0]))
+		}
+		v, err := in.eval(pair[1], e)
+		if err != nil {
+			return nil, err
+		}
+		le.vars[s] = v
+	}
+	return in.evalBody(args[1:], le)
+}
+
+func formBegin(in *Interp, args []Value, e *env) (Value, error) {
+	return in.evalBody(args, e)
+}
+
+func formWhile(in *Interp, args []Value, e *env) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("while: bad syntax")
+	}
+	for {
+		c, err := in.eval(args[0], e)
+		if err != nil {
+			return nil, err
+		}
+		if !Truth(c) {
+			return nil, nil
+		}
+		if _, err := in.evalBody(args[1:], e); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func formAnd(in *Interp, args []Value, e *env) (Value, error) {
+	var v Value = true
+	var err error
+	for _, a := range args {
+		if v, err = in.eval(a, e); err != nil {
+			return nil, err
+		}
+		if !Truth(v) {
+			return v, nil
+		}
+	}
+	return v, nil
+}
+
+func formOr(in *Interp, args []Value, e *env) (Value, error) {
+	for _, a := range args {
+		v, err := in.eval(a, e)
+		if err != nil {
+			return nil, err
+		}
+		if Truth(v) {
+			return v, nil
+		}
+	}
+	return false, nil
+}
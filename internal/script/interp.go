@@ -0,0 +1,239 @@
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Value is a value in a script: nil (the empty list), bool, int, string,
+// Symbol, []Value (a list), *Lambda or Func.
+type Value interface{}
+
+// Symbol is a name in a script.
+type Symbol string
+
+// Func is a function implemented in Go.
+type Func func(args []Value) (Value, error)
+
+// Lambda is a function defined in a script.
+type Lambda struct {
+	name   string
+	params []Symbol
+	body   []Value
+	env    *env
+}
+
+// maxDepth limits the nesting of function calls, so that runaway recursion
+// in a script is reported as an error instead of exhausting the stack.
+const maxDepth = 1000
+
+// maxSteps limits the expressions evaluated by one call of Eval or Call,
+// so that a script stuck in a loop is reported as an error instead of
+// hanging the editor.
+const maxSteps = 10000000
+
+type env struct {
+	vars  map[Symbol]Value
+	outer *env
+}
+
+func newEnv(outer *env) *env {
+	return &env{vars: make(map[Symbol]Value), outer: outer}
+}
+
+func (e *env) lookup(s Symbol) (*env, bool) {
+	for ; e != nil; e = e.outer {
+		if _, ok := e.vars[s]; ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Interp is a script interpreter. Its methods must not be called concurrently.
+type Interp struct {
+	global   *env
+	depth    int
+	maxSteps int  // expressions one call of Eval or Call may evaluate
+	steps    int  // expressions left to evaluate in the current call
+	running  bool // inside Eval or Call
+}
+
+// New returns an interpreter with the built-in functions defined.
+func New() *Interp {
+	in := &Interp{global: newEnv(nil), maxSteps: maxSteps}
+	for name, fn := range builtins {
+		in.global.vars[Symbol(name)] = fn
+	}
+	return in
+}
+
+// Define sets the global variable name to v.
+func (in *Interp) Define(name string, v Value) {
+	in.global.vars[Symbol(name)] = v
+}
+
+// Eval evaluates the expressions in src and returns the value of the last one.
+func (in *Interp) Eval(src string) (Value, error) {
+	exprs, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.start()()
+	var v Value
+	for _, x := range exprs {
+		v, err = in.eval(x, in.global)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// EvalFile evaluates the script in file.
+func (in *Interp) EvalFile(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if _, err := in.Eval(string(b)); err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+	return nil
+}
+
+// Call calls the function fn with args.
+func (in *Interp) Call(fn Value, args ...Value) (Value, error) {
+	defer in.start()()
+	return in.apply(fn, args)
+}
+
+// start renews the step budget unless a call of Eval or Call, which may
+// have called back into Go, is already running. It returns a function
+// ending the call.
+func (in *Interp) start() (end func()) {
+	if in.running {
+		return func() {}
+	}
+	in.running = true
+	in.steps = in.maxSteps
+	return func() { in.running = false }
+}
+
+func (in *Interp) eval(x Value, e *env) (Value, error) {
+	if in.steps--; in.steps < 0 {
+		return nil, fmt.Errorf("evaluation took more than %d steps", in.maxSteps)
+	}
+	switch x := x.(type) {
+	case Symbol:
+		if d, ok := e.lookup(x); ok {
+			return d.vars[x], nil
+		}
+		return nil, fmt.Errorf("undefined: %v", x)
+	case []Value:
+		if len(x) == 0 {
+			return nil, nil
+		}
+		if s, ok := x[0].(Symbol); ok {
+			if form, ok := specialForms[s]; ok {
+				return form(in, x[1:], e)
+			}
+		}
+		fn, err := in.eval(x[0], e)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]Value, len(x)-1)
+		for i, a := range x[1:] {
+			if args[i], err = in.eval(a, e); err != nil {
+				return nil, err
+			}
+		}
+		return in.apply(fn, args)
+	default:
+		return x, nil
+	}
+}
+
+func (in *Interp) apply(fn Value, args []Value) (Value, error) {
+	switch fn := fn.(type) {
+	case Func:
+		return fn(args)
+	case *Lambda:
+		if len(args) != len(fn.params) {
+			return nil, fmt.Errorf("%v: %d arguments; expected %d", fn, len(args), len(fn.params))
+		}
+		if in.depth >= maxDepth {
+			return nil, fmt.Errorf("%v: calls nested too deeply", fn)
+		}
+		in.depth++
+		defer func() { in.depth-- }()
+
+		e := newEnv(fn.env)
+		for i, p := range fn.params {
+			e.vars[p] = args[i]
+		}
+		return in.evalBody(fn.body, e)
+	default:
+		return nil, fmt.Errorf("not a function: %v", Format(fn))
+	}
+}
+
+func (in *Interp) evalBody(body []Value, e *env) (Value, error) {
+	var v Value
+	var err error
+	for _, x := range body {
+		if v, err = in.eval(x, e); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (fn *Lambda) String() string {
+	if fn.name == "" {
+		return "lambda"
+	}
+	return fn.name
+}
+
+// Truth reports whether v counts as true: anything except #f and the empty list.
+func Truth(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case []Value:
+		return len(v) > 0
+	}
+	return true
+}
+
+// Format returns the printed representation of v. Strings are printed
+// without quotes.
+func Format(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "()"
+	case bool:
+		if v {
+			return "#t"
+		}
+		return "#f"
+	case []Value:
+		s := make([]string, len(v))
+		for i, x := range v {
+			s[i] = Format(x)
+			if str, ok := x.(string); ok {
+				s[i] = fmt.Sprintf("%q", str)
+			}
+		}
+		return "(" + strings.Join(s, " ") + ")"
+	case Func:
+		return "builtin"
+	default:
+		return fmt.Sprint(v)
+	}
+}
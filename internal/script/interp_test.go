@@ -0,0 +1,148 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	for _, tc := range []struct {
+		src, out string
+	}{
+		{`42`, "42"},
+		{`"hello"`, "hello"},
+		{`#t`, "#t"},
+		{`'(1 "a" b)`, `(1 "a" b)`},
+		{`()`, "()"},
+		{`(+ 1 2 3)`, "6"},
+		{`(- 5)`, "-5"},
+		{`(- 10 3 2)`, "5"},
+		{`(* 2 (/ 9 2) (% 7 4))`, "24"},
+		{`(< 1 2 3)`, "#t"},
+		{`(>= 1 2)`, "#f"},
+		{`(if (= 1 1) "yes" "no")`, "yes"},
+		{`(if #f 1)`, "()"},
+		{`(if '() 1 2)`, "2"},
+		{`(if 0 1 2)`, "1"},
+		{`(cond ((= 1 2) "a") ((= 1 1) "b") (else "c"))`, "b"},
+		{`(cond (#f "a") (else "c"))`, "c"},
+		{`(define x 3) (set! x (+ x 1)) x`, "4"},
+		{`(define (sq x) (* x x)) (sq 7)`, "49"},
+		{`((lambda (a b) (string-append a b)) "foo" "bar")`, "foobar"},
+		{`(let ((a 1) (b 2)) (+ a b))`, "3"},
+		{`(define (fact n) (if (= n 0) 1 (* n (fact (- n 1))))) (fact 10)`, "3628800"},
+		{`(define i 0) (define s 0) (while (< i 5) (set! s (+ s i)) (set! i (+ i 1))) s`, "10"},
+		{`(and 1 2)`, "2"},
+		{`(and 1 #f 2)`, "#f"},
+		{`(or #f 3)`, "3"},
+		{`(or)`, "#f"},
+		{`(not '())`, "#t"},
+		{`(equal? '(1 "a") (list 1 "a"))`, "#t"},
+		{`(equal? "a" "b")`, "#f"},
+		{`(string-length "héllo")`, "5"},
+		{`(substring "héllo" 1 3)`, "él"},
+		{`(string-index "héllo" "llo")`, "2"},
+		{`(string-index "hello" "x")`, "-1"},
+		{`(number->string 12)`, "12"},
+		{`(string->number " 12 ")`, "12"},
+		{`(string->number "x")`, "#f"},
+		{`(cons 1 '(2 3))`, "(1 2 3)"},
+		{`(car '(1 2))`, "1"},
+		{`(cdr '(1 2))`, "(2)"},
+		{`(null? (cdr '(1)))`, "#t"},
+		{`(length '(1 2 3))`, "3"},
+		{"; comment\n(+ 1 ; another\n 1)", "2"},
+		{`"a\"b\n"`, "a\"b\n"},
+		{`(define (make-counter) (let ((n 0)) (lambda () (set! n (+ n 1)) n)))
+		  (define c (make-counter)) (c) (c)`, "2"},
+	} {
+		v, err := New().Eval(tc.src)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tc.src, err)
+			continue
+		}
+		if got := Format(v); got != tc.out {
+			t.Errorf("Eval(%q) is %v; expected %v", tc.src, got, tc.out)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{`(`, "line 1: missing ')'"},
+		{`)`, "line 1: unexpected ')'"},
+		{"\n\"abc", "line 2: unterminated string"},
+		{`x`, "undefined: x"},
+		{`(set! x 1)`, "set!: undefined: x"},
+		{`(1 2)`, "not a function: 1"},
+		{`(+ 1 "a")`, `+: argument 2 is a; expected a number`},
+		{`(/ 1 0)`, "/: division by zero"},
+		{`(car '())`, "car: empty list"},
+		{`(define (f x) x) (f)`, "f: 0 arguments; expected 1"},
+		{`(define (f) (f)) (f)`, "f: calls nested too deeply"},
+		{`(error "bad" 1)`, "bad 1"},
+		{`(substring "abc" 2 5)`, "substring: range 2,5 out of bounds"},
+	} {
+		_, err := New().Eval(tc.src)
+		if err == nil {
+			t.Errorf("Eval(%q) succeeded; expected error %q", tc.src, tc.err)
+			continue
+		}
+		if err.Error() != tc.err {
+			t.Errorf("Eval(%q) failed with %q; expected %q", tc.src, err, tc.err)
+		}
+	}
+}
+
+func TestDefine(t *testing.T) {
+	in := New()
+	var got []string
+	in.Define("record", Func(func(args []Value) (Value, error) {
+		for _, a := range args {
+			got = append(got, Format(a))
+		}
+		return len(args), nil
+	}))
+	in.Define("greeting", "hello")
+	if _, err := in.Eval(`(define (f name) (record greeting name))`); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	fn, err := in.Eval(`f`)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	v, err := in.Call(fn, "world")
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if v != 2 {
+		t.Errorf("Call returned %v; expected 2", Format(v))
+	}
+	if s := strings.Join(got, " "); s != "hello world" {
+		t.Errorf("recorded %q; expected %q", s, "hello world")
+	}
+	if s := fmt.Sprint(fn); s != "f" {
+		t.Errorf("function is printed as %q; expected %q", s, "f")
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	in := New()
+	in.maxSteps = 1000
+	if _, err := in.Eval(`(while #t ())`); err == nil {
+		t.Errorf("endless loop didn't fail")
+	}
+	if _, err := in.Eval(`(define (loop) (while #t ())) (+ 1 1)`); err != nil {
+		t.Errorf("Eval failed after an endless loop: %v", err)
+	}
+	fn, err := in.Eval(`loop`)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if _, err := in.Call(fn); err == nil {
+		t.Errorf("endless loop called didn't fail")
+	}
+}
@@ -0,0 +1,157 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// parser reads expressions from the source text of a script.
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// Parse returns the expressions in src.
+func Parse(src string) ([]Value, error) {
+	p := &parser{src: src, line: 1}
+	var exprs []Value
+	for {
+		p.skipspace()
+		if p.pos >= len(p.src) {
+			return exprs, nil
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, x)
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() rune {
+	c, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return c
+}
+
+func (p *parser) next() rune {
+	c, w := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.pos += w
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipspace skips white space and comments, which run from ';' to the end of the line.
+func (p *parser) skipspace() {
+	for p.pos < len(p.src) {
+		c := p.peek()
+		switch {
+		case c == ';':
+			for p.pos < len(p.src) && p.peek() != '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(c):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) expr() (Value, error) {
+	p.skipspace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of script")
+	}
+	switch c := p.peek(); c {
+	case '(':
+		p.next()
+		var list []Value
+		for {
+			p.skipspace()
+			if p.pos >= len(p.src) {
+				return nil, p.errorf("missing ')'")
+			}
+			if p.peek() == ')' {
+				p.next()
+				return list, nil
+			}
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, x)
+		}
+	case ')':
+		return nil, p.errorf("unexpected ')'")
+	case '\'':
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return []Value{Symbol("quote"), x}, nil
+	case '"':
+		return p.str()
+	default:
+		return p.atom()
+	}
+}
+
+func (p *parser) str() (Value, error) {
+	p.next() // opening quote
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.next()
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				break
+			}
+			switch e := p.next(); e {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(e)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+func (p *parser) atom() (Value, error) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.peek()
+		if unicode.IsSpace(c) || c == '(' || c == ')' || c == '"' || c == ';' {
+			break
+		}
+		p.next()
+	}
+	s := p.src[start:p.pos]
+	switch s {
+	case "#t":
+		return true, nil
+	case "#f":
+		return false, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	return Symbol(s), nil
+}
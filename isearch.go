@@ -0,0 +1,182 @@
+package main
+
+import (
+	"unicode"
+
+	"github.com/fhs/edward/internal/regexp"
+)
+
+// Keys starting an incremental search, or moving it to the next match.
+const (
+	Kisearchfwd  = 0x13 // ^S: search forward
+	Kisearchback = 0x12 // ^R: search backward
+)
+
+// An isearch is an incremental search in progress in a window. The text
+// searched for is typed into the window, shown at the end of the tag, and
+// the body selection follows the match as each key is typed. Escape ends
+// the search, restoring the selection the body had when it started, and
+// newline ends it leaving the match selected.
+type isearch struct {
+	q0, q1 int    // body selection when the search started
+	text   []rune // text searched for
+	back   bool   // searching backward
+	at     int    // matches start at or after at going forward, at or before it going backward
+	m0, m1 int    // match selected in the body, or the original selection
+	tagq   int    // tag size before the text was added
+}
+
+// The last text searched for, which ^S or ^R searches for again when
+// typed before any other text.
+var lastisearch []rune
+
+// isearchkey handles the key r typed into w if it starts or continues an
+// incremental search, returning whether r was used. A key other than
+// those of the search, or a change of the body selection by other means,
+// ends the search and leaves the key to be handled as usual.
+func (w *Window) isearchkey(r rune) bool {
+	is := w.isearch
+	if is == nil {
+		if r != Kisearchfwd && r != Kisearchback {
+			return false
+		}
+		w.startisearch(r == Kisearchback)
+		return true
+	}
+	if w.body.q0 != is.m0 || w.body.q1 != is.m1 {
+		w.isearch = nil
+		return false
+	}
+	switch {
+	case r == Kisearchfwd || r == Kisearchback:
+		is.back = r == Kisearchback
+		if len(is.text) == 0 {
+			w.isearchinsert(lastisearch)
+			w.isearchfind()
+			return true
+		}
+		if is.back {
+			is.at = is.m0 - 1
+		} else {
+			is.at = is.m1
+		}
+		w.isearchfind()
+	case r == 0x1B: // Escape
+		w.isearchinsert(nil) // removes the text from the tag
+		w.isearch = nil
+		w.body.Show(is.q0, is.q1, true)
+	case r == '\n':
+		w.isearch = nil
+	case r == Kscrolloneup || r == Kscrollonedown:
+		return false // scrolling leaves the search going
+	case r == 0x08: // ^H: erase character
+		if len(is.text) > 0 {
+			w.isearchinsert(is.text[:len(is.text)-1])
+			w.isearchfind()
+		}
+	case r == '\t' || unicode.IsPrint(r):
+		w.isearchinsert(append(is.text[:len(is.text):len(is.text)], r))
+		w.isearchfind()
+	default:
+		w.isearch = nil
+		return false
+	}
+	return true
+}
+
+// startisearch starts an incremental search from the body selection.
+func (w *Window) startisearch(back bool) {
+	w.Commit(&w.tag)
+	q := w.tag.Nc()
+	if q > 0 && w.tag.file.ReadC(q-1) != ' ' && w.tag.file.ReadC(q-1) != '\t' {
+		w.tag.Insert(q, []rune{' '}, true)
+	}
+	is := &isearch{
+		q0:   w.body.q0,
+		q1:   w.body.q1,
+		back: back,
+		m0:   w.body.q0,
+		m1:   w.body.q1,
+		tagq: w.tag.Nc(),
+	}
+	if back {
+		is.at = w.body.q0 - 1
+	} else {
+		is.at = w.body.q1
+	}
+	w.isearch = is
+	w.tag.SetSelect(is.tagq, is.tagq)
+}
+
+// isearchinsert replaces the text searched for with text, changing the
+// end of the tag to match.
+func (w *Window) isearchinsert(text []rune) {
+	is := w.isearch
+	t := &w.tag
+	if n := t.Nc(); n == is.tagq+len(is.text) {
+		t.Delete(is.tagq, n, true)
+	} else {
+		is.tagq = n // the tag was changed underneath us
+	}
+	t.Insert(is.tagq, text, true)
+	t.SetSelect(is.tagq+len(text), is.tagq+len(text))
+	is.text = append([]rune(nil), text...)
+}
+
+// isearchfind selects the match of the text searched for nearest to the
+// start of the search in its direction, wrapping around at the end of the
+// body. With no text, the original selection is restored. If there's no
+// match, the selection is left as it was.
+func (w *Window) isearchfind() {
+	is := w.isearch
+	t := &w.body
+	t.file.loadrest()
+	q0, q1, ok := is.q0, is.q1, true
+	if len(is.text) > 0 {
+		lastisearch = is.text
+		q0, q1, ok = isearchmatch(t, is.text, is.at, is.back)
+		if !ok {
+			return
+		}
+	}
+	is.m0, is.m1 = q0, q1
+	t.Show(q0, q1, true)
+	w.SetTag()
+}
+
+// isearchmatch finds text in t, ignoring case if it has no upper case
+// letters. Going forward, it returns the first match starting at or after
+// at, and going backward the last one starting at or before at, wrapping
+// around at the end of t if there's none.
+func isearchmatch(t Texter, text []rune, at int, back bool) (q0, q1 int, ok bool) {
+	pat := regexp.QuoteMeta(string(text))
+	if smartcase(text) {
+		pat = "(?i)" + pat
+	}
+	re, err := rxcompile(pat)
+	if err != nil {
+		return 0, 0, false
+	}
+	n := t.Nc()
+	if back {
+		// A match ending by at+len(text) starts at or before at; the
+		// matcher in runesb.go scans back from there.
+		end := min(max(at+len(text), 0), n)
+		if rs := re.rxbexecute(t, end, 1); len(rs) > 0 {
+			return rs[0].q0, rs[0].q1, true
+		}
+		if rs := re.rxbexecute(t, n, 1); len(rs) > 0 {
+			return rs[0].q0, rs[0].q1, true
+		}
+		return 0, 0, false
+	}
+	if at <= n {
+		if rs := re.rxexecute(t, nil, at, n, 1); len(rs) > 0 {
+			return rs[0][0].q0, rs[0][0].q1, true
+		}
+	}
+	if rs := re.rxexecute(t, nil, 0, n, 1); len(rs) > 0 {
+		return rs[0][0].q0, rs[0][0].q1, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsearchmatch(t *testing.T) {
+	text := &Text{file: NewFile("")}
+	text.file.InsertAt(0, []rune("abc Abc abc\nx.c\n"))
+	for _, tc := range []struct {
+		text   string
+		at     int
+		back   bool
+		q0, q1 int
+		ok     bool
+	}{
+		{"abc", 0, false, 0, 3, true},
+		{"abc", 1, false, 4, 7, true},
+		{"Abc", 1, false, 4, 7, true},
+		{"Abc", 5, false, 4, 7, true}, // wraps around
+		{"abc", 9, false, 0, 3, true},
+		{"abc", 8, true, 8, 11, true},
+		{"abc", 7, true, 4, 7, true},
+		{"abc", 0, true, 0, 3, true},
+		{"Abc", 3, true, 4, 7, true}, // wraps around
+		{"abc", -1, true, 8, 11, true},
+		{".c", 0, false, 13, 15, true},
+		{"c\nx", 0, false, 10, 13, true},
+		{"xyz", 0, false, 0, 0, false},
+		{"xyz", 0, true, 0, 0, false},
+	} {
+		q0, q1, ok := isearchmatch(text, []rune(tc.text), tc.at, tc.back)
+		if q0 != tc.q0 || q1 != tc.q1 || ok != tc.ok {
+			t.Errorf("isearchmatch(%q, %d, %v) is %d, %d, %v; want %d, %d, %v",
+				tc.text, tc.at, tc.back, q0, q1, ok, tc.q0, tc.q1, tc.ok)
+		}
+	}
+}
+
+func TestIsearchkey(t *testing.T) {
+	defer func() { lastisearch = nil }()
+
+	for _, tc := range []struct {
+		name   string
+		dot    Range
+		keys   string
+		sel    Range
+		search string // text at the end of the tag
+		active bool
+	}{
+		{"Start", Range{3, 3}, "\x13", Range{3, 3}, "", true},
+		{"Forward", Range{3, 3}, "\x13t", Range{14, 15}, "t", true},
+		{"Extend", Range{3, 3}, "\x13te", Range{16, 18}, "te", true},
+		{"Next", Range{3, 3}, "\x13t\x13", Range{16, 17}, "t", true},
+		{"Backward", Range{20, 20}, "\x12t", Range{19, 20}, "t", true},
+		{"BackwardNext", Range{20, 20}, "\x12t\x12", Range{16, 17}, "t", true},
+		{"ExtendBackward", Range{20, 20}, "\x12tr", Range{24, 26}, "tr", true},
+		{"Wrap", Range{30, 30}, "\x13is", Range{2, 4}, "is", true},
+		{"SmartCase", Range{1, 1}, "\x13T", Range{0, 1}, "T", true},
+		{"IgnoreCase", Range{0, 0}, "\x13t", Range{0, 1}, "t", true},
+		{"NoMatch", Range{3, 3}, "\x13tz", Range{14, 15}, "tz", true},
+		{"Erase", Range{3, 3}, "\x13tz\b\b", Range{3, 3}, "", true},
+		{"Escape", Range{3, 5}, "\x13te\x1b", Range{3, 5}, "", false},
+		{"Newline", Range{3, 3}, "\x13te\n", Range{16, 18}, "te", false},
+		{"Again", Range{0, 0}, "\x13ad\n\x13\x13", Range{28, 30}, "ad", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := makeSkeletonWindowModel(tc.dot, "test")
+			lastisearch = nil
+			for _, r := range tc.keys {
+				w.body.Type(r)
+			}
+			if got := (Range{w.body.q0, w.body.q1}); got != tc.sel {
+				t.Errorf("body selection is %v; want %v", got, tc.sel)
+			}
+			tag := string(w.tag.View(0, w.tag.Nc()))
+			if !strings.HasSuffix(tag, " "+tc.search) {
+				t.Errorf("tag is %q; want it to end with %q", tag, " "+tc.search)
+			}
+			if got := w.isearch != nil; got != tc.active {
+				t.Errorf("search in progress is %v; want %v", got, tc.active)
+			}
+		})
+	}
+}
+
+func TestIsearchkeyEnds(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{3, 3}, "test")
+	defer func() { lastisearch = nil }()
+
+	for _, r := range "\x13te" {
+		w.body.Type(r)
+	}
+	// Selecting text with the mouse ends the search, leaving the key to
+	// be typed into the body.
+	w.body.SetSelect(0, 0)
+	w.body.Type('X')
+	w.body.TypeCommit()
+	if w.isearch != nil {
+		t.Errorf("search still in progress after the selection changed")
+	}
+	if got, want := string(w.body.View(0, w.body.Nc())), "X"+contents; got != want {
+		t.Errorf("body is %q; want %q", got, want)
+	}
+}
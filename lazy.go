@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/fhs/edward/internal/file"
+)
+
+// Files larger than lazythreshold() are loaded lazily: Load reads only
+// the first part of the file and the rest is appended in the background,
+// a chunk at a time, with the window locked only while a chunk is added.
+// Operations that need the whole file, such as addresses, searches, Undo
+// and Put, call File.loadrest to finish loading first.
+
+const defaultLazyThreshold = 64 << 20
+
+// Sizes of the part of the file read by Load and of each later chunk.
+var (
+	lazyfirst = 256 << 10
+	lazychunk = 4 << 20
+)
+
+// lazyload is the state of a File being loaded lazily.
+type lazyload struct {
+	fd      *os.File
+	size    int64 // size of the disk file
+	n       int64 // bytes read so far
+	enc     file.Encoding
+	pending []byte    // bytes read but not yet decoded
+	misfit  bool      // some of the bytes decoded weren't in enc
+	h       hash.Hash // hash of the bytes read, if the File's hash is to be set
+	u       *Undo     // undo record for the first part, extended by later parts
+}
+
+func lazythreshold() int64 {
+	if cfg.LazyLoad > 0 {
+		return cfg.LazyLoad
+	}
+	return defaultLazyThreshold
+}
+
+// percent returns how much of the file has been read.
+func (ld *lazyload) percent() int64 {
+	if ld.size <= 0 {
+		return 100
+	}
+	return 100 * ld.n / ld.size
+}
+
+// decode returns the runes in the pending bytes, keeping back any bytes
+// that can't be decoded without the ones following them.
+func (ld *lazyload) decode(final bool) []rune {
+	n := len(ld.pending)
+	if !final {
+		n = ld.enc.Cut(ld.pending)
+	}
+	if !ld.enc.Fits(ld.pending[:n]) {
+		ld.misfit = true
+	}
+	b := ld.enc.Decode(ld.pending[:n])
+	ld.pending = append([]byte(nil), ld.pending[n:]...)
+	r, _, _ := cvttorunes(b, len(b))
+	return r
+}
+
+// loadLazy loads the first part of the disk file fd into the empty
+// Text.file and starts loading the rest in the background. It takes
+// ownership of fd.
+func (t *Text) loadLazy(filename string, fd *os.File, size int64, sethash bool) (int, error) {
+	b := make([]byte, lazyfirst)
+	n, err := io.ReadFull(fd, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		fd.Close()
+		return 0, warnError(nil, "error reading file %s: %v", filename, err)
+	}
+	b = b[:n]
+	enc := file.DetectEncoding(b[:file.Encoding{}.Cut(b)])
+	if enc.Charset == file.Hex {
+		// The offsets in the hex dump are easier to get right in one go.
+		defer fd.Close()
+		return t.loadReader(0, filename, io.MultiReader(bytes.NewReader(b), fd), sethash)
+	}
+
+	f := t.file
+	ld := &lazyload{
+		fd:      fd,
+		size:    size,
+		n:       int64(n),
+		enc:     enc,
+		pending: b,
+	}
+	if sethash {
+		ld.h = sha1.New()
+		ld.h.Write(b)
+		f.encoding = enc
+		f.misencoded = false
+	}
+	r := ld.decode(false)
+	if enc.Charset == file.UTF8BOM {
+		ld.enc.Charset = file.UTF8 // only the first part has the byte order mark
+	}
+
+	t.file.SetDir(false)
+	t.w.filemenu = true
+	f.InsertAt(0, r)
+	if f.seq > 0 {
+		ld.u = f.delta[len(f.delta)-1]
+	}
+	f.loading = ld
+	t.w.ref.Inc()
+	go t.w.lazyloader(f, ld)
+	return len(r), nil
+}
+
+// lazyloader appends the rest of the disk file to f, which is shown in
+// w, until it's all loaded or f stops being loaded or shown.
+func (w *Window) lazyloader(f *File, ld *lazyload) {
+	for {
+		w.Lock('F')
+		if f.loading == ld && len(f.text) > 0 {
+			f.loadchunk()
+			w.Unlock()
+			continue
+		}
+		if f.loading == ld {
+			f.stoploading()
+		}
+		w.Close() // drop the reference taken by loadLazy
+		w.Unlock()
+		return
+	}
+}
+
+// loadchunk appends the next chunk of the disk file being loaded lazily.
+// It reports whether there's more to load. Called with the window locked.
+func (f *File) loadchunk() bool {
+	ld := f.loading
+	if ld == nil {
+		return false
+	}
+	b := make([]byte, lazychunk)
+	n, err := io.ReadFull(ld.fd, b)
+	b = b[:n]
+	ld.n += int64(n)
+	if ld.h != nil {
+		ld.h.Write(b)
+	}
+	ld.pending = append(ld.pending, b...)
+	done := err != nil
+	if done && err != io.EOF && err != io.ErrUnexpectedEOF {
+		warning(nil, "error reading file %s: %v\n", f.name, err)
+	}
+
+	// The chunk is part of loading the file, not an edit: it gets no undo
+	// record of its own and leaves the File clean.
+	r := ld.decode(done)
+	f.Commit()
+	p0 := f.b.nc()
+	f.b.Insert(p0, r)
+	if ld.u != nil {
+		ld.u.n += len(r)
+	}
+	for _, t := range f.text {
+		t.inserted(p0, r)
+	}
+	if ld.misfit && ld.h != nil && !f.misencoded {
+		// The encoding was detected from the first part of the file, and
+		// doesn't fit this chunk: its text isn't what's on disk, and
+		// writing it back would change the file.
+		f.misencoded = true
+		warning(nil, "%s: not all of the file is %v; it can't be Put\n", f.name, ld.enc)
+	}
+	if done {
+		if ld.h != nil {
+			f.hash.Set(ld.h.Sum(nil))
+		}
+		f.stoploading()
+	}
+	if f.curtext != nil && f.curtext.w != nil {
+		f.curtext.w.SetTag()
+	}
+	for _, t := range f.text {
+		if t.w != nil && t.w.display != nil {
+			t.w.display.Flush()
+		}
+	}
+	return !done
+}
+
+// loadrest finishes loading the File if it's being loaded lazily.
+// Called with the window locked.
+func (f *File) loadrest() {
+	for f.loadchunk() {
+	}
+}
+
+// stoploading stops loading the File lazily, leaving it incomplete.
+func (f *File) stoploading() {
+	if f.loading != nil {
+		f.loading.fd.Close()
+		f.loading = nil
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fhs/edward/internal/config"
+	"github.com/fhs/edward/internal/edwoodtest"
+	"github.com/fhs/edward/internal/file"
+)
+
+func TestLoadLazy(t *testing.T) {
+	configureGlobals()
+	defer func(c *config.Config, first, chunk int) {
+		cfg, lazyfirst, lazychunk = c, first, chunk
+	}(cfg, lazyfirst, lazychunk)
+	cfg = &config.Config{LazyLoad: 100}
+	lazyfirst, lazychunk = 100, 333
+
+	dir, err := ioutil.TempDir("", "edwood.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name, in, out string
+	}{
+		{"UTF-8", strings.Repeat("Hello, 世界\r\n", 200), strings.Repeat("Hello, 世界\n", 200)},
+		{"Latin1", strings.Repeat("caf\xe9\n", 200), strings.Repeat("café\n", 200)},
+		{"UTF-16", "\xFF\xFE" + strings.Repeat("h\x00\xe9\x00\n\x00", 200), strings.Repeat("hé\n", 200)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "tmpfile")
+			if err := ioutil.WriteFile(filename, []byte(tc.in), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			display := edwoodtest.NewDisplay()
+			w := NewWindow().initHeadless(nil)
+			w.display = display
+			w.col = new(Column)
+			w.body.display = display
+			w.body.fr = &MockFrame{}
+			w.tag.display = display
+			w.tag.fr = &MockFrame{}
+
+			w.Lock('M')
+			if _, err := w.body.Load(0, filename, true); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if w.body.file.loading == nil || w.body.Nc() >= len([]rune(tc.out)) {
+				t.Errorf("Load read the whole file")
+			}
+			w.Unlock()
+
+			// Let the file load in the background.
+			for deadline := time.Now().Add(5 * time.Second); ; {
+				w.Lock('M')
+				done := w.body.file.loading == nil
+				w.Unlock()
+				if done {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("file not loaded in time")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if got := string(w.body.file.b); got != tc.out {
+				t.Errorf("loaded text %q; expected %q", got, tc.out)
+			}
+			if !w.body.file.hash.Eq(file.CalcHash([]byte(tc.in))) {
+				t.Errorf("hash of lazily loaded file is wrong")
+			}
+			if w.body.file.Dirty() {
+				t.Errorf("lazily loaded file is dirty")
+			}
+		})
+	}
+}
+
+func TestLoadrest(t *testing.T) {
+	configureGlobals()
+	defer func(c *config.Config, first, chunk int) {
+		cfg, lazyfirst, lazychunk = c, first, chunk
+	}(cfg, lazyfirst, lazychunk)
+	cfg = &config.Config{LazyLoad: 100}
+	lazyfirst, lazychunk = 100, 100
+
+	dir, err := ioutil.TempDir("", "edwood.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tmpfile")
+	want := strings.Repeat("0123456789\n", 1000)
+	if err := ioutil.WriteFile(filename, []byte(want), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	display := edwoodtest.NewDisplay()
+	w := NewWindow().initHeadless(nil)
+	w.display = display
+	w.col = new(Column)
+	w.body.display = display
+	w.body.fr = &MockFrame{}
+	w.tag.display = display
+	w.tag.fr = &MockFrame{}
+
+	w.Lock('M')
+	defer w.Unlock()
+	if _, err := w.body.Load(0, filename, true); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	w.body.file.loadrest()
+	if w.body.file.loading != nil {
+		t.Errorf("file still loading after loadrest")
+	}
+	if got := string(w.body.file.b); got != want {
+		t.Errorf("loaded %v runes; expected %v", len(got), len(want))
+	}
+}
+
+func TestLoadLazyMisfit(t *testing.T) {
+	configureGlobals()
+	defer func(c *config.Config, first, chunk int) {
+		cfg, lazyfirst, lazychunk = c, first, chunk
+	}(cfg, lazyfirst, lazychunk)
+	cfg = &config.Config{LazyLoad: 100}
+	lazyfirst, lazychunk = 100, 100
+	defer func() {
+		warningsMu.Lock()
+		warnings = []*Warning{}
+		warningsMu.Unlock()
+	}()
+
+	dir, err := ioutil.TempDir("", "edwood.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first lazyfirst bytes of each file decide its encoding, which
+	// doesn't fit a byte that follows.
+	for _, tc := range []struct {
+		name, in string
+	}{
+		{"Latin1", strings.Repeat("abcdefghi\n", 20) + "caf\xe9\n"},
+		{"NUL", strings.Repeat("abcdefghi\n", 20) + "a\x00b\n"},
+		{"LF", strings.Repeat("abcdefgh\r\n", 20) + "abc\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "tmpfile")
+			if err := ioutil.WriteFile(filename, []byte(tc.in), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			display := edwoodtest.NewDisplay()
+			w := NewWindow().initHeadless(nil)
+			w.display = display
+			w.col = new(Column)
+			w.body.display = display
+			w.body.fr = &MockFrame{}
+			w.tag.display = display
+			w.tag.fr = &MockFrame{}
+
+			w.Lock('M')
+			defer w.Unlock()
+			if _, err := w.body.Load(0, filename, true); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			w.body.file.loadrest()
+			if !w.body.file.misencoded {
+				t.Errorf("file not marked as misencoded")
+			}
+			f := w.body.file
+			if err := putfile(f, 0, f.Size(), filename); err == nil {
+				t.Errorf("putfile succeeded")
+			}
+			if b, err := ioutil.ReadFile(filename); err != nil || string(b) != tc.in {
+				t.Errorf("file changed to %q (error %v); expected %q", b, err, tc.in)
+			}
+
+			// Loading the file again starts afresh.
+			if err := ioutil.WriteFile(filename, []byte("abc\n"), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			f.b = nil
+			if _, err := w.body.Load(0, filename, true); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if f.misencoded {
+				t.Errorf("file still marked as misencoded after loading it again")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"time"
+	"unicode"
 
 	"9fans.net/go/plan9"
 	"9fans.net/go/plan9/client"
@@ -90,6 +91,9 @@ func look3(t *Text, q0 int, q1 int, external bool) {
 	if ct == nil {
 		seltext = t
 	}
+	if looksnarf(t, q0) {
+		return
+	}
 	e, expanded := expand(t, q0, q1)
 	if !external && t.w != nil && t.w.nopen[QWevent] > 0 {
 		// send alphanumeric expansion to external client
@@ -146,6 +150,9 @@ func look3(t *Text, q0 int, q1 int, external bool) {
 		}
 		return
 	}
+	if t.what == Body && t.w != nil && t.w.treelook(q0) {
+		return
+	}
 	if plumbsendfid != nil {
 		m, err := look3Message(t, q0, q1)
 		if err != nil {
@@ -177,7 +184,7 @@ func look3(t *Text, q0 int, q1 int, external bool) {
 		n = e.q1 - e.q0
 		r = make([]rune, n)
 		t.file.b.Read(e.q0, r)
-		if search(ct, r[:n]) && e.jump && t.w != nil {
+		if search(ct, r[:n], searchmode{}) && e.jump && t.w != nil {
 			t.w.display.MoveTo(ct.fr.Ptofchar(getP0(ct.fr)).Add(image.Pt(4, ct.fr.DefaultFontHeight()-4)))
 		}
 	}
@@ -190,7 +197,7 @@ func look3Message(t *Text, q0, q1 int) (*plumb.Message, error) {
 	m := &plumb.Message{
 		Src:  "acme",
 		Dst:  "",
-		Dir:  t.AbsDirName(""),
+		Dir:  localdir(t.AbsDirName("")),
 		Type: "text",
 	}
 	if q1 == q0 {
@@ -278,17 +285,71 @@ func plumbshow(m *plumb.Message) {
 	xfidlog(w, "new")
 }
 
+// searchmode selects how Look matches text.
+type searchmode struct {
+	fold bool // ignore case
+	word bool // match whole words only
+}
+
+// parselookflags splits the flags off the argument of Look. The flags
+// are -i to ignore case, -s to ignore case unless the text searched for
+// has upper case letters, and -w to match whole words only; they may be
+// combined, as in -iw. Smart case is settled when the text searched for
+// is known, so it's returned separately.
+func parselookflags(arg string) (mode searchmode, smart bool, rest string) {
+	for {
+		arg = strings.TrimLeft(arg, " \t")
+		i := strings.IndexAny(arg, " \t")
+		if i < 0 {
+			i = len(arg)
+		}
+		flag := arg[:i]
+		if len(flag) < 2 || flag[0] != '-' || strings.Trim(flag[1:], "isw") != "" {
+			return mode, smart, arg
+		}
+		for _, c := range flag[1:] {
+			switch c {
+			case 'i':
+				mode.fold = true
+			case 's':
+				smart = true
+			case 'w':
+				mode.word = true
+			}
+		}
+		arg = arg[i:]
+	}
+}
+
+// smartcase reports whether a smart case search for r ignores case.
+func smartcase(r []rune) bool {
+	for _, c := range r {
+		if unicode.IsUpper(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// search looks for r in ct, starting at the end of the selection and
+// wrapping around at the end of the text. It selects and shows the first
+// match, returning whether there was one.
+//
 // TODO(flux): This just looks for r in ct; a regexp could do it too,
 // using our buffer streaming thing.  Frankly, even scanning the buffer
 // using the streamer would probably be easier to read/more idiomatic.
-func search(ct *Text, r []rune) bool {
+func search(ct *Text, r []rune, mode searchmode) bool {
 	var (
 		n, maxn int
 	)
+	ct.file.loadrest()
 	n = len(r)
 	if n == 0 || n > ct.Nc() {
 		return false
 	}
+	if mode.fold || mode.word {
+		return searchmatch(ct, r, mode)
+	}
 	if 2*n > RBUFSIZE {
 		warning(nil, "string too long\n")
 		return false
@@ -332,14 +393,7 @@ func search(ct *Text, r []rune) bool {
 		}
 		limit := min(len(s), bi+n)
 		if runes.Equal(s[bi:limit], r) {
-			if ct.w != nil {
-				ct.Show(q, q+n, true)
-				ct.w.SetTag()
-			} else {
-				ct.q0 = q
-				ct.q1 = q + n
-			}
-			seltext = ct
+			searchfound(ct, q, q+n)
 			return true
 		}
 		nb--
@@ -352,6 +406,53 @@ func search(ct *Text, r []rune) bool {
 	return false
 }
 
+// searchmatch is search for modes that can't be done by scanning for the
+// first rune of r. It compares r at each position in turn, in the same
+// order as search.
+func searchmatch(ct *Text, r []rune, mode searchmode) bool {
+	text := ct.View(0, ct.Nc())
+	n := len(r)
+	match := func(q int) bool {
+		for i, c := range r {
+			d := text[q+i]
+			if c != d && !(mode.fold && unicode.ToLower(c) == unicode.ToLower(d)) {
+				return false
+			}
+		}
+		if mode.word {
+			if q > 0 && isalnum(text[q-1]) || q+n < len(text) && isalnum(text[q+n]) {
+				return false
+			}
+		}
+		return true
+	}
+	for q := ct.q1; q+n <= len(text); q++ {
+		if match(q) {
+			searchfound(ct, q, q+n)
+			return true
+		}
+	}
+	for q := 0; q < ct.q1 && q+n <= len(text); q++ {
+		if match(q) {
+			searchfound(ct, q, q+n)
+			return true
+		}
+	}
+	return false
+}
+
+// searchfound selects the match q0, q1 found by search.
+func searchfound(ct *Text, q0, q1 int) {
+	if ct.w != nil {
+		ct.Show(q0, q1, true)
+		ct.w.SetTag()
+	} else {
+		ct.q0 = q0
+		ct.q1 = q1
+	}
+	seltext = ct
+}
+
 func isfilec(r rune) bool {
 	Lx := ".-+/:@"
 	if isalnum(r) {
@@ -370,7 +471,49 @@ func cleanrname(rs []rune) []rune {
 	return r
 }
 
+// expandremote expands the text at q0, q1 to the name of a file handled
+// by a provider, with an optional address after a colon. The colon after
+// the scheme would stop expandfile.
+func expandremote(t *Text, q0 int, q1 int, e *Expand) bool {
+	if q1 == q0 {
+		for q1 < t.file.Size() {
+			c := t.ReadC(q1)
+			if c == ' ' || c == '\t' || c == '\n' || !(isfilec(c) || isaddrc(c)) {
+				break
+			}
+			q1++
+		}
+		for q0 > 0 && isfilec(t.ReadC(q0-1)) {
+			q0--
+		}
+	}
+	if q1 <= q0 {
+		return false
+	}
+	rb := make([]rune, q1-q0)
+	t.file.b.Read(q0, rb)
+	s := string(rb)
+	if !isremote(s) {
+		return false
+	}
+	e.q0 = q0
+	e.q1 = q1
+	e.name = s
+	e.at = t
+	e.a0 = q1
+	e.a1 = q1
+	if i := strings.Index(s, "://") + len("://"); strings.IndexByte(s[i:], ':') >= 0 {
+		n := len([]rune(s[:i+strings.IndexByte(s[i:], ':')]))
+		e.name = string(rb[:n])
+		e.a0 = q0 + n + 1
+	}
+	return true
+}
+
 func expandfile(t *Text, q0 int, q1 int, e *Expand) (success bool) {
+	if expandremote(t, q0, q1, e) {
+		return true
+	}
 	amax := q1
 	if q1 == q0 {
 		colon := int(-1)
@@ -550,7 +693,7 @@ func openfile(t *Text, e *Expand) *Window {
 		}
 	} else {
 		w = lookfile(e.name)
-		if w == nil && !filepath.IsAbs(e.name) {
+		if w == nil && !filepath.IsAbs(e.name) && !isremote(e.name) {
 			// Unrooted path in new window.
 			// This can happen if we type a pwd-relative path
 			// in the topmost tag or the column tags.
@@ -596,6 +739,7 @@ func openfile(t *Text, e *Expand) *Window {
 		eval = false
 	} else {
 		eval = true
+		t.file.loadrest()
 		r, eval, _ = address(true, t, Range{-1, -1}, Range{t.q0, t.q1}, e.a0, e.a1, e.agetc, eval)
 		if r.q0 > r.q1 {
 			eval = false
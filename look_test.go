@@ -188,3 +188,85 @@ func textSetSelection(t *Text, buf string) {
 	t.q1 = popRune('»')
 	t.file = &File{b: Buffer(b)}
 }
+
+func TestParselookflags(t *testing.T) {
+	for _, tc := range []struct {
+		arg   string
+		mode  searchmode
+		smart bool
+		rest  string
+	}{
+		{"", searchmode{}, false, ""},
+		{"foo", searchmode{}, false, "foo"},
+		{"-i foo", searchmode{fold: true}, false, "foo"},
+		{"-w foo bar", searchmode{word: true}, false, "foo bar"},
+		{"-s -w foo", searchmode{word: true}, true, "foo"},
+		{"-iw", searchmode{fold: true, word: true}, false, ""},
+		{"-x foo", searchmode{}, false, "-x foo"},
+		{"- foo", searchmode{}, false, "- foo"},
+		{"-i -foo", searchmode{fold: true}, false, "-foo"},
+	} {
+		mode, smart, rest := parselookflags(tc.arg)
+		if mode != tc.mode || smart != tc.smart || rest != tc.rest {
+			t.Errorf("parselookflags(%q) = %v, %v, %q; want %v, %v, %q",
+				tc.arg, mode, smart, rest, tc.mode, tc.smart, tc.rest)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	const text = "Foo food foo\nFOO\n"
+
+	for _, tc := range []struct {
+		name  string
+		q1    int // end of selection, where the search starts
+		s     string
+		mode  searchmode
+		found bool
+		want  Range
+	}{
+		{"Literal", 0, "foo", searchmode{}, true, Range{4, 7}},
+		{"LiteralWrap", 12, "Foo", searchmode{}, true, Range{0, 3}},
+		{"Fold", 1, "foo", searchmode{fold: true}, true, Range{4, 7}},
+		{"FoldWrap", 14, "foo", searchmode{fold: true}, true, Range{0, 3}},
+		{"FoldUpper", 10, "FOO", searchmode{fold: true}, true, Range{13, 16}},
+		{"Word", 0, "foo", searchmode{word: true}, true, Range{9, 12}},
+		{"FoldWord", 1, "foo", searchmode{fold: true, word: true}, true, Range{9, 12}},
+		{"FoldWordWrap", 14, "foo", searchmode{fold: true, word: true}, true, Range{0, 3}},
+		{"WordNotFound", 0, "fo", searchmode{word: true}, false, Range{0, 0}},
+		{"FoldNotFound", 0, "bar", searchmode{fold: true}, false, Range{0, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ct := &Text{file: NewFile("")}
+			ct.file.InsertAt(0, []rune(text))
+			ct.q0, ct.q1 = 0, tc.q1
+
+			found := search(ct, []rune(tc.s), tc.mode)
+			if found != tc.found {
+				t.Fatalf("search found %v; want %v", found, tc.found)
+			}
+			if found {
+				if got := (Range{ct.q0, ct.q1}); got != tc.want {
+					t.Errorf("search selected %v; want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSmartcase(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		fold bool
+	}{
+		{"foo", true},
+		{"foo.go", true},
+		{"Foo", false},
+		{"ÉTÉ", false},
+		{"été", true},
+	} {
+		if got := smartcase([]rune(tc.s)); got != tc.fold {
+			t.Errorf("smartcase(%q) is %v; want %v", tc.s, got, tc.fold)
+		}
+	}
+}
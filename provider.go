@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A Provider gives access to files that are not on the local file system.
+// Such files are named by URLs of the form scheme://host/path, where the
+// scheme selects the provider.
+type Provider interface {
+	// Read returns the contents of the file at path on host. If the file
+	// is a directory, it returns the names of the files in it instead,
+	// with a trailing slash on the names of directories.
+	Read(host, path string) (data []byte, names []string, isdir bool, err error)
+
+	// Write replaces the contents of the file at path on host with data.
+	Write(host, path string, data []byte) error
+}
+
+// providers holds the providers, by scheme.
+var providers = map[string]Provider{
+	"ssh": &shellProvider{func(host, script string) []string {
+		return []string{"ssh", "--", host, script}
+	}},
+	"docker": &shellProvider{func(host, script string) []string {
+		return []string{"docker", "exec", "-i", "--", host, "sh", "-c", script}
+	}},
+}
+
+// splitremote splits a name handled by a provider into the provider,
+// the host and the path. It returns ok == false if name is a local file name.
+// Names with an empty host, or one starting with a dash, which the helper
+// program would take for an option, aren't handled by providers.
+func splitremote(name string) (p Provider, host, file string, ok bool) {
+	i := strings.Index(name, "://")
+	if i <= 0 {
+		return nil, "", "", false
+	}
+	p, ok = providers[name[:i]]
+	if !ok {
+		return nil, "", "", false
+	}
+	host = name[i+len("://"):]
+	file = "/"
+	if j := strings.IndexByte(host, '/'); j >= 0 {
+		host, file = host[:j], host[j:]
+	}
+	if host == "" || host[0] == '-' {
+		return nil, "", "", false
+	}
+	return p, host, file, true
+}
+
+// isremote reports whether name is handled by a provider.
+func isremote(name string) bool {
+	_, _, _, ok := splitremote(name)
+	return ok
+}
+
+// localdir returns the directory dir, or the current directory if dir is
+// remote. Commands run from a window, and the messages it plumbs, need a
+// local directory.
+func localdir(dir string) string {
+	if isremote(dir) {
+		return wdir
+	}
+	return dir
+}
+
+// joinremote joins name to the directory of the remote file dir.
+// Directory names end in a slash.
+func joinremote(dir, name string) string {
+	i := strings.Index(dir, "://") + len("://")
+	j := strings.IndexByte(dir[i:], '/')
+	if j < 0 {
+		return dir + path.Join("/", name)
+	}
+	prefix, p := dir[:i+j], dir[i+j:]
+	if !strings.HasSuffix(p, "/") {
+		p = path.Dir(p)
+	}
+	return prefix + path.Join(p, name)
+}
+
+// shellProvider implements Provider by running shell commands on the host
+// through a helper program such as ssh.
+type shellProvider struct {
+	// argv returns the command that runs the shell script on host,
+	// with its standard input and output connected to ours.
+	argv func(host, script string) []string
+}
+
+func (sp *shellProvider) run(host, script string, stdin []byte) ([]byte, error) {
+	args := sp.argv(host, script)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %v", args[0], msg)
+		}
+		return nil, fmt.Errorf("%v: %v", args[0], err)
+	}
+	return out, nil
+}
+
+func (sp *shellProvider) Read(host, file string) ([]byte, []string, bool, error) {
+	q := shellquote(file)
+	out, err := sp.run(host, "if test -d "+q+"; then echo d; ls -1pA "+q+"; else echo f; cat "+q+"; fi", nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	i := bytes.IndexByte(out, '\n')
+	if i < 0 {
+		return nil, nil, false, fmt.Errorf("short reply from host")
+	}
+	kind, out := string(out[:i]), out[i+1:]
+	if kind == "f" {
+		return out, nil, false, nil
+	}
+	names := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(names) == 1 && names[0] == "" {
+		names = nil
+	}
+	sort.Strings(names)
+	return nil, names, true, nil
+}
+
+func (sp *shellProvider) Write(host, file string, data []byte) error {
+	_, err := sp.run(host, "cat > "+shellquote(file), data)
+	return err
+}
+
+// shellquote quotes s for the Bourne shell.
+func shellquote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
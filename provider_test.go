@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// localProvider runs the shell scripts of a shellProvider on this machine.
+var localProvider = &shellProvider{func(host, script string) []string {
+	return []string{"sh", "-c", script}
+}}
+
+func TestSplitremote(t *testing.T) {
+	providers["local"] = localProvider
+	defer delete(providers, "local")
+
+	for _, tc := range []struct {
+		name       string
+		host, file string
+		ok         bool
+	}{
+		{"/home/gopher/a.go", "", "", false},
+		{"a.go", "", "", false},
+		{"http://example.com/a", "", "", false},
+		{"://host/a", "", "", false},
+		{"local:///a", "", "", false},
+		{"local://-oProxyCommand=sh${IFS}-c${IFS}id/x", "", "", false},
+		{"local://host", "host", "/", true},
+		{"local://host/", "host", "/", true},
+		{"local://user@host/home/a.go", "user@host", "/home/a.go", true},
+	} {
+		p, host, file, ok := splitremote(tc.name)
+		if ok != tc.ok || host != tc.host || file != tc.file {
+			t.Errorf("splitremote(%q) is %q, %q, %v; expected %q, %q, %v",
+				tc.name, host, file, ok, tc.host, tc.file, tc.ok)
+		}
+		if ok && p != localProvider {
+			t.Errorf("splitremote(%q) returned provider %v", tc.name, p)
+		}
+	}
+}
+
+func TestProviderArgv(t *testing.T) {
+	for scheme, want := range map[string]string{
+		"ssh":    "ssh -- host true",
+		"docker": "docker exec -i -- host sh -c true",
+	} {
+		if got := strings.Join(providers[scheme].(*shellProvider).argv("host", "true"), " "); got != want {
+			t.Errorf("%v command is %q; expected %q", scheme, got, want)
+		}
+	}
+}
+
+func TestJoinremote(t *testing.T) {
+	for _, tc := range []struct {
+		dir, name, out string
+	}{
+		{"ssh://host", "a.go", "ssh://host/a.go"},
+		{"ssh://host/src/", "a.go", "ssh://host/src/a.go"},
+		{"ssh://host/src/b.go", "a.go", "ssh://host/src/a.go"},
+		{"ssh://host/src/b.go", "../a.go", "ssh://host/a.go"},
+	} {
+		if out := joinremote(tc.dir, tc.name); out != tc.out {
+			t.Errorf("joinremote(%q, %q) is %q; expected %q", tc.dir, tc.name, out, tc.out)
+		}
+	}
+}
+
+func TestShellProvider(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no Bourne shell")
+	}
+	dir, err := ioutil.TempDir("", "edward.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "it's a file")
+	want := "Hello, 世界\n"
+	if err := localProvider.Write("host", filename, []byte(want)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, names, isdir, err := localProvider.Read("host", filename)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if isdir || names != nil || string(data) != want {
+		t.Errorf("Read returned %q, %q, %v; expected %q, nil, false", data, names, isdir, want)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	_, names, isdir, err = localProvider.Read("host", dir)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if wantNames := []string{"it's a file", "sub/"}; !isdir || !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("Read of directory returned %q, %v; expected %q, true", names, isdir, wantNames)
+	}
+
+	_, _, _, err = localProvider.Read("host", filepath.Join(dir, "non-existent"))
+	if err == nil || !strings.HasPrefix(err.Error(), "sh: ") {
+		t.Errorf("Read of non-existent file returned error %v; expected sh: ...", err)
+	}
+}
+
+func TestLoadRemote(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no Bourne shell")
+	}
+	providers["local"] = localProvider
+	defer delete(providers, "local")
+
+	dir, err := ioutil.TempDir("", "edward.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tmpfile")
+	want := "remote file's content\n"
+	if err = ioutil.WriteFile(filename, []byte(want), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	text := emptyText()
+	_, err = text.Load(0, "local://host"+filename, true)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if out := string(text.file.b); out != want {
+		t.Errorf("loaded text %q; expected %q", out, want)
+	}
+}
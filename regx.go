@@ -10,7 +10,11 @@ type AcmeRegexp struct {
 }
 
 // rxcompile parses a regular expression and returns a regular expression object
-// that can be used to match against text.
+// that can be used to match against text. The expression may start with
+// flags, as in (?i)abc to ignore case; they work wherever a regular
+// expression is accepted, such as in addresses and Edit commands. Matching
+// whole words can be done with \b, which knows only ASCII letters
+// and digits, as in \babc\b.
 func rxcompile(r string) (*AcmeRegexp, error) {
 	re, err := regexp.CompileAcme(r)
 	if err != nil {
@@ -22,7 +26,9 @@ func rxcompile(r string) (*AcmeRegexp, error) {
 }
 
 // rxexecute searches forward in r[start:end] (from beginning of the slice to the end)
-// and returns at most n matches. If r is nil, it is derived from t.
+// and returns at most n matches. If r is nil, it is derived from t, whose
+// View is the text of its buffer rather than a copy, so searching a large
+// file allocates nothing in proportion to its size.
 func (re *AcmeRegexp) rxexecute(t Texter, r []rune, start int, end int, n int) []RangeSet {
 	if r == nil {
 		r = t.View(0, t.Nc())
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fhs/edward/internal/file"
+)
+
+// Name of the window previewing the changes of Replace, in the directory
+// holding the files changed.
+const replaceName = "+Replace"
+
+// A replacement is a change proposed by Replace.
+type replacement struct {
+	q0, q1    int    // the matched text
+	line, col int    // position of the match, counting from 1
+	old, new  []rune // matched text and its replacement
+}
+
+// A replacejob is a Replace waiting to be applied from its preview window.
+type replacejob struct {
+	dir   string
+	re    *AcmeRegexp
+	rhs   []rune
+	files map[string][]replacement // by path relative to dir
+	sums  map[string]file.Hash     // of the text each file had, by path relative to dir
+}
+
+// replacejobs holds the jobs previewed in +Replace windows, by the File of
+// the window. It is guarded by row.lk.
+var replacejobs = make(map[*File]*replacejob)
+
+// replacejobof returns the job of the +Replace window whose body is t, or
+// nil. The job is dropped if the window has been renamed.
+func replacejobof(t *Text) *replacejob {
+	job, ok := replacejobs[t.file]
+	if !ok {
+		return nil
+	}
+	if t.file.name != filepath.Join(job.dir, replaceName) {
+		delete(replacejobs, t.file)
+		return nil
+	}
+	return job
+}
+
+// The start of a line in a +Replace window: path:line:col:
+var replacelinere = regexp.MustCompile(`^(.+?):([0-9]+):([0-9]+): `)
+
+// replacex implements the Replace command. With an argument of the form
+// /regexp/text/, as for the Edit s command, it previews the substitution
+// applied to every match in the files under the directory of the window,
+// with one line per change in a +Replace window. Files and directories
+// ignored by .gitignore files are skipped. Without an argument in a
+// +Replace window, it applies the changes still listed, loading the files
+// into windows and leaving them dirty, to be checked and Put.
+func replacex(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		arg, _ = getarg(argt, false, false)
+	}
+	if arg == "" {
+		if job := replacejobof(&et.w.body); job != nil {
+			job.apply(&et.w.body)
+			return
+		}
+		warning(nil, "usage: Replace /regexp/text/\n")
+		return
+	}
+
+	cp := newCmdParser([]rune(arg))
+	delim := cp.getch()
+	if !okdelim(delim) {
+		warning(nil, "Replace: bad delimiter %q\n", delim)
+		return
+	}
+	pat, err := cp.getregexp(delim)
+	if err != nil {
+		warning(nil, "Replace: %v\n", err)
+		return
+	}
+	rhs, err := cp.getrhs(delim, 's')
+	if err != nil {
+		warning(nil, "Replace: %v\n", err)
+		return
+	}
+	re, err := rxcompile(pat)
+	if err != nil {
+		warning(nil, "Replace: bad regexp %q: %v\n", pat, err)
+		return
+	}
+	dir := et.w.body.AbsDirName("")
+	if isremote(dir) {
+		warning(nil, "Replace: can't change files in remote directory %v\n", dir)
+		return
+	}
+
+	job := &replacejob{
+		dir: dir,
+		re:  re,
+		rhs: []rune(rhs),
+	}
+	bodies := openbodies()
+
+	name := filepath.Join(dir, replaceName)
+	w := lookfile(name)
+	if w == nil {
+		w = row.col.Add(nil, -1)
+		defer w.HandleInput()
+		w.filemenu = false
+		w.SetName(name)
+		xfidlog(w, "new")
+	}
+	if w.body.file != et.w.body.file {
+		w.Lock('E')
+		defer w.Unlock()
+	}
+	delete(replacejobs, w.body.file)
+	job.show(&w.body, "searching…\n")
+	w.walkwindow(func(stop <-chan struct{}) {
+		job.files, job.sums = replacefiles(dir, re, job.rhs, bodies, stop)
+	}, func() {
+		replacejobs[w.body.file] = job
+		job.show(&w.body, job.preview())
+	})
+}
+
+// show replaces the text of the +Replace window t of job with s.
+func (job *replacejob) show(t *Text, s string) {
+	t.Delete(0, t.Nc(), true)
+	t.Insert(0, []rune(s), true)
+	t.file.Clean()
+	t.SetSelect(0, 0)
+	if t.fr != nil {
+		t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+	}
+	t.w.SetTag()
+}
+
+// replacefiles returns the changes that substituting rhs for the matches
+// of re makes to the files under dir, and the sums of the text they were
+// found in, by path relative to dir. Files open in windows are changed
+// there, so their changes are found in their text in bodies, by file
+// name, instead of on disk. Closing stop ends the walk early.
+func replacefiles(dir string, re *AcmeRegexp, rhs []rune, bodies map[string][]rune, stop <-chan struct{}) (map[string][]replacement, map[string]file.Hash) {
+	var mu sync.Mutex
+	files := make(map[string][]replacement)
+	sums := make(map[string]file.Hash)
+	walktree(dir, stop, func(p string) {
+		text, ok := bodies[p]
+		if !ok {
+			text = readtext(p)
+		}
+		reps := findreplacements(text, re, rhs)
+		if len(reps) == 0 {
+			return
+		}
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return
+		}
+		name = filepath.ToSlash(name)
+		sum := textsum(text)
+		mu.Lock()
+		files[name] = reps
+		sums[name] = sum
+		mu.Unlock()
+	})
+	return files, sums
+}
+
+// textsum returns the sum of text used to tell whether a file has changed
+// since its changes were previewed.
+func textsum(text []rune) file.Hash {
+	return file.CalcHash([]byte(string(text)))
+}
+
+// openbodies returns copies of the text of the windows open on files, by
+// file name.
+func openbodies() map[string][]rune {
+	bodies := make(map[string][]rune)
+	for _, w := range row.col.w {
+		t := &w.body
+		if t.file.IsDir() || t.file.name == "" {
+			continue
+		}
+		name := filepath.Clean(t.file.name)
+		if _, ok := bodies[name]; ok {
+			continue
+		}
+		t.file.loadrest()
+		w.Commit(t)
+		bodies[name] = append([]rune(nil), t.View(0, t.Nc())...)
+	}
+	return bodies
+}
+
+// readtext returns the text of the file p as it would be shown in a
+// window, or nil if it can't be read or isn't text.
+func readtext(p string) []rune {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+	enc := file.DetectEncoding(b)
+	if enc.Charset == file.Hex {
+		return nil
+	}
+	b = enc.Decode(b)
+	if bytes.IndexByte(b, 0) >= 0 {
+		return nil
+	}
+	text, _, _ := cvttorunes(b, len(b))
+	return text
+}
+
+// findreplacements returns the changes that substituting rhs for the
+// matches of re makes to text.
+func findreplacements(text []rune, re *AcmeRegexp, rhs []rune) []replacement {
+	var reps []replacement
+	names := re.SubexpNames()
+	line, linestart, q := 1, 0, 0
+	for _, sel := range re.rxexecute(nil, text, 0, len(text), -1) {
+		for ; q < sel[0].q0; q++ {
+			if text[q] == '\n' {
+				line++
+				linestart = q + 1
+			}
+		}
+		reps = append(reps, replacement{
+			q0:   sel[0].q0,
+			q1:   sel[0].q1,
+			line: line,
+			col:  sel[0].q0 - linestart + 1,
+			old:  append([]rune(nil), text[sel[0].q0:sel[0].q1]...),
+			new:  substitute(text, sel, names, rhs),
+		})
+	}
+	return reps
+}
+
+// sortedfiles returns the paths of the files changed by job in lexical order.
+func (job *replacejob) sortedfiles() []string {
+	var names []string
+	for name := range job.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// preview returns the contents of the +Replace window for job: a line
+// path:line:col: old ⇒ new for each change.
+func (job *replacejob) preview() string {
+	var sb strings.Builder
+	for _, name := range job.sortedfiles() {
+		for _, rep := range job.files[name] {
+			fmt.Fprintf(&sb, "%s:%d:%d: %s ⇒ %s\n", name, rep.line, rep.col, oneline(rep.old), oneline(rep.new))
+		}
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("no matches\n")
+	}
+	return sb.String()
+}
+
+// oneline returns r with its newlines shown as ⏎, to fit on one line.
+func oneline(r []rune) string {
+	return strings.Replace(string(r), "\n", "⏎", -1)
+}
+
+// replacekey identifies a change by file and position.
+type replacekey struct {
+	name      string
+	line, col int
+}
+
+// selectedreplacements returns the changes still listed in the +Replace window t.
+func selectedreplacements(t *Text) map[replacekey]bool {
+	keys := make(map[replacekey]bool)
+	for _, line := range strings.Split(string(t.View(0, t.Nc())), "\n") {
+		m := replacelinere.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		l, _ := strconv.Atoi(m[2])
+		c, _ := strconv.Atoi(m[3])
+		keys[replacekey{m[1], l, c}] = true
+	}
+	return keys
+}
+
+// apply makes the changes of job that are still listed in its +Replace
+// window t. Each file is changed in its window, which is opened if need
+// be, as a single undoable change.
+func (job *replacejob) apply(t *Text) {
+	keys := selectedreplacements(t)
+	nfiles, nchanges := 0, 0
+	for _, name := range job.sortedfiles() {
+		want := make(map[replacekey]bool)
+		for _, rep := range job.files[name] {
+			k := replacekey{name, rep.line, rep.col}
+			if keys[k] {
+				want[k] = true
+			}
+		}
+		if len(want) == 0 {
+			continue
+		}
+		n := job.applyfile(filepath.Join(job.dir, filepath.FromSlash(name)), name, want)
+		if n > 0 {
+			nfiles++
+			nchanges += n
+		}
+	}
+	delete(replacejobs, t.file)
+	warning(nil, "Replace: %d changes in %d files\n", nchanges, nfiles)
+}
+
+// applyfile makes the changes in want to the file with the given path,
+// called name in the +Replace window. The changes are found again in the
+// text of the file's window, and only made if the text is still what was
+// previewed: otherwise the lines and columns listed may no longer be those
+// of the changes kept. It returns the number of changes made.
+func (job *replacejob) applyfile(path, name string, want map[replacekey]bool) int {
+	w := lookfile(path)
+	if w == nil {
+		w = row.col.Add(nil, -1)
+		w.SetName(path)
+		xfidlog(w, "new")
+		if _, err := w.body.Load(0, path, true); err != nil {
+			// Load has warned.
+			w.Lock('E')
+			w.col.Close(w, true)
+			w.Unlock()
+			return 0
+		}
+		defer w.HandleInput()
+		w.body.file.Clean()
+		w.autoindent = *globalAutoIndent
+	}
+	w.Lock('E')
+	defer w.Unlock()
+
+	t := &w.body
+	if t.file.ReadOnly() {
+		warning(nil, "Replace: %s is read-only; not changed\n", path)
+		return 0
+	}
+	t.file.loadrest()
+	text := t.View(0, t.Nc())
+	if !textsum(text).Eq(job.sums[name]) {
+		warning(nil, "Replace: %s changed since the preview; not changed\n", path)
+		return 0
+	}
+	alleditinit(w)
+	n := 0
+	for _, rep := range findreplacements(text, job.re, job.rhs) {
+		if !want[replacekey{name, rep.line, rep.col}] {
+			continue
+		}
+		t.file.elog.Replace(rep.q0, rep.q1, rep.new)
+		n++
+	}
+	if n > 0 {
+		seq++
+		allupdate(w)
+	}
+	return n
+}
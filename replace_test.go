@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fhs/edward/internal/file"
+)
+
+func TestFindreplacements(t *testing.T) {
+	re, err := rxcompile(`(\w+)@(\w+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := findreplacements([]rune("mail a@b\nor\n  cc@dd, x@y\n"), re, []rune(`\2 at \1`))
+	want := []replacement{
+		{q0: 5, q1: 8, line: 1, col: 6, old: []rune("a@b"), new: []rune("b at a")},
+		{q0: 14, q1: 19, line: 3, col: 3, old: []rune("cc@dd"), new: []rune("dd at cc")},
+		{q0: 21, q1: 24, line: 3, col: 10, old: []rune("x@y"), new: []rune("y at x")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findreplacements returned\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReplacejobPreview(t *testing.T) {
+	job := &replacejob{files: map[string][]replacement{}}
+	if got, want := job.preview(), "no matches\n"; got != want {
+		t.Errorf("preview of no changes is %q; want %q", got, want)
+	}
+	job.files["b/c.go"] = []replacement{{line: 2, col: 1, old: []rune("x\ny"), new: []rune("z")}}
+	job.files["a.go"] = []replacement{
+		{line: 1, col: 3, old: []rune("foo"), new: []rune("bar")},
+		{line: 4, col: 1, old: []rune("foo"), new: []rune("")},
+	}
+	want := "a.go:1:3: foo ⇒ bar\n" +
+		"a.go:4:1: foo ⇒ \n" +
+		"b/c.go:2:1: x⏎y ⇒ z\n"
+	if got := job.preview(); got != want {
+		t.Errorf("preview is %q; want %q", got, want)
+	}
+}
+
+func TestSelectedreplacements(t *testing.T) {
+	text := &Text{file: NewFile("")}
+	text.file.InsertAt(0, []rune("a.go:1:3: foo ⇒ bar\n"+
+		"deleted by hand\n"+
+		"b/c:d.go:12:1: x:1:2: y ⇒ z\n"))
+	got := selectedreplacements(text)
+	want := map[replacekey]bool{
+		{"a.go", 1, 3}:      true,
+		{"b/c:d.go", 12, 1}: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectedreplacements returned %v; want %v", got, want)
+	}
+}
+
+func TestReplacejobApply(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "test")
+	defer func() {
+		warningsMu.Lock()
+		warnings = []*Warning{}
+		warningsMu.Unlock()
+	}()
+
+	re, err := rxcompile("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &replacejob{
+		re:    re,
+		rhs:   []rune("T"),
+		files: map[string][]replacement{"test": findreplacements([]rune(contents), re, []rune("T"))},
+		sums:  map[string]file.Hash{"test": textsum([]rune(contents))},
+	}
+	// Leave out the changes to the second line.
+	var lines []string
+	for _, line := range strings.SplitAfter(job.preview(), "\n") {
+		if !strings.HasPrefix(line, "test:2:") {
+			lines = append(lines, line)
+		}
+	}
+	preview := &Text{file: NewFile("")}
+	preview.file.InsertAt(0, []rune(strings.Join(lines, "")))
+	replacejobs[preview.file] = job
+
+	job.apply(preview)
+
+	want := "This is a\nshort text\nTo Try addressing\n"
+	if got := string(w.body.View(0, w.body.Nc())); got != want {
+		t.Errorf("body after Replace is %q; want %q", got, want)
+	}
+	if !w.body.file.Dirty() {
+		t.Errorf("body is clean after Replace")
+	}
+	if _, ok := replacejobs[preview.file]; ok {
+		t.Errorf("job still waiting after it was applied")
+	}
+	w.Undo(true)
+	if got := string(w.body.View(0, w.body.Nc())); got != contents {
+		t.Errorf("body after Undo is %q; want %q", got, contents)
+	}
+}
+
+func TestReplacefiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edward-replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, s := range map[string]string{
+		"open.txt":   "tat\n",
+		"closed.txt": "at\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	re, err := rxcompile("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies := map[string][]rune{
+		filepath.Join(dir, "open.txt"): []rune("x\nt\n"), // changed in its window
+	}
+	files, sums := replacefiles(dir, re, []rune("T"), bodies, nil)
+	var got []string
+	for name, reps := range files {
+		for _, rep := range reps {
+			got = append(got, fmt.Sprintf("%s:%d:%d", name, rep.line, rep.col))
+		}
+	}
+	sort.Strings(got)
+	if want := []string{"closed.txt:1:2", "open.txt:2:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes are %q; want %q", got, want)
+	}
+	if !sums["open.txt"].Eq(textsum([]rune("x\nt\n"))) {
+		t.Errorf("sum of open.txt isn't that of its text in its window")
+	}
+	if !sums["closed.txt"].Eq(textsum([]rune("at\n"))) {
+		t.Errorf("sum of closed.txt isn't that of its text on disk")
+	}
+}
+
+func TestReplacejobApplyStale(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "test")
+	defer func() {
+		warningsMu.Lock()
+		warnings = []*Warning{}
+		warningsMu.Unlock()
+	}()
+
+	re, err := rxcompile("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &replacejob{
+		re:    re,
+		rhs:   []rune("T"),
+		files: map[string][]replacement{"test": findreplacements([]rune(contents), re, []rune("T"))},
+		sums:  map[string]file.Hash{"test": textsum([]rune(contents))},
+	}
+	// Keep only the changes to the second line, then add a copy of it
+	// above it: the copy is now where the listed changes point.
+	var lines []string
+	for _, line := range strings.SplitAfter(job.preview(), "\n") {
+		if strings.HasPrefix(line, "test:2:") {
+			lines = append(lines, line)
+		}
+	}
+	preview := &Text{file: NewFile("")}
+	preview.file.InsertAt(0, []rune(strings.Join(lines, "")))
+	w.body.Insert(10, []rune("short text\n"), true)
+	want := string(w.body.View(0, w.body.Nc()))
+
+	job.apply(preview)
+
+	if got := string(w.body.View(0, w.body.Nc())); got != want {
+		t.Errorf("body changed since the preview became %q; want %q", got, want)
+	}
+}
+
+func TestReplacejobof(t *testing.T) {
+	w := makeSkeletonWindowModel(Range{0, 0}, "/p/+Replace Del Snarf | Look")
+	job := &replacejob{dir: "/p"}
+	replacejobs[w.body.file] = job
+	defer delete(replacejobs, w.body.file)
+
+	if got := replacejobof(&w.body); got != job {
+		t.Errorf("got job %v; want %v", got, job)
+	}
+	w.body.file.SetName("/p/notes")
+	if got := replacejobof(&w.body); got != nil {
+		t.Errorf("got job %v after rename; want nil", got)
+	}
+	if _, ok := replacejobs[w.body.file]; ok {
+		t.Errorf("job of renamed window not dropped")
+	}
+}
@@ -158,6 +158,7 @@ func (r *Row) dump() (*dumpfile.Content, error) {
 			},
 			Position: 0,
 			Font:     fontname,
+			Geometry: w.geometry(),
 		})
 		dw := dump.Windows[len(dump.Windows)-1]
 
@@ -189,6 +190,27 @@ func (r *Row) dump() (*dumpfile.Content, error) {
 	return dump, nil
 }
 
+// geometry returns the size and placement of the OS window showing w.
+// Devdraw doesn't tell us where the window manager put the window, so
+// the position is the one last asked for, if any.
+func (w *Window) geometry() *dumpfile.Geometry {
+	if w.display == nil {
+		return nil
+	}
+	r := w.display.ScreenImage().R()
+	g := &dumpfile.Geometry{
+		Width:  r.Dx(),
+		Height: r.Dy(),
+		Screen: w.screen,
+	}
+	if w.winsize != "" {
+		if req, err := dumpfile.ParseWinsize(w.winsize); err == nil {
+			g.Position = req.Position
+		}
+	}
+	return g
+}
+
 // loadhelper breaks out common load file parsing functionality for selected row
 // types.
 func (row *Row) loadhelper(win *dumpfile.Window) error {
@@ -201,18 +223,25 @@ func (row *Row) loadhelper(win *dumpfile.Window) error {
 		return fmt.Errorf("bad window tag in dump file %q", win.Tag)
 	}
 
+	size := *winsize
+	if win.Geometry != nil {
+		size = win.Geometry.Winsize()
+	}
 	var w *Window
 	if win.Type != dumpfile.Zerox {
-		w = c.Add(nil, y)
+		w = c.add(nil, y, size)
 		defer w.HandleInput()
 	} else {
-		w = c.Add(lookfile(subl[0]), y)
+		w = c.add(lookfile(subl[0]), y, size)
 		defer w.HandleInput()
 	}
 	if w == nil {
 		// Why is this not an error?
 		return nil
 	}
+	if win.Geometry != nil {
+		w.screen = win.Geometry.Screen
+	}
 
 	if win.Type != dumpfile.Zerox {
 		w.SetName(subl[0])
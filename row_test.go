@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"image"
 	"io/ioutil"
 	"math"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"9fans.net/go/plan9"
 	"9fans.net/go/plan9/client"
 	"github.com/fhs/edward/internal/dumpfile"
+	"github.com/fhs/edward/internal/edwoodtest"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -410,6 +412,34 @@ func TestRowLookupWin(t *testing.T) {
 	}
 }
 
+func TestWindowGeometry(t *testing.T) {
+	for _, tc := range []struct {
+		winsize, screen string
+		want            *dumpfile.Geometry
+	}{
+		{"", "", &dumpfile.Geometry{Width: 800, Height: 600}},
+		{"300x200", "", &dumpfile.Geometry{Width: 800, Height: 600}},
+		{"300x200@5,6", "2", &dumpfile.Geometry{
+			Width:    800,
+			Height:   600,
+			Position: &image.Point{5, 6},
+			Screen:   "2",
+		}},
+	} {
+		w := &Window{
+			display: edwoodtest.NewDisplay(),
+			winsize: tc.winsize,
+			screen:  tc.screen,
+		}
+		if diff := cmp.Diff(tc.want, w.geometry()); diff != "" {
+			t.Errorf("geometry for -W %q mismatch (-want +got):\n%s", tc.winsize, diff)
+		}
+	}
+	if g := (&Window{}).geometry(); g != nil {
+		t.Errorf("window without display has geometry %v", g)
+	}
+}
+
 // jsonEscapePath escapes blackslashes in Windows path.
 func jsonEscapePath(s string) string {
 	return strings.Replace(s, "\\", "\\\\", -1)
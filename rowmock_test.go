@@ -53,7 +53,9 @@ func MakeWindowScaffold(content *dumpfile.Content) {
 		updateText(&w.tag, &serwin.Tag, display)
 		w.body.file.SetName(strings.SplitN(serwin.Tag.Buffer, " ", 2)[0])
 		w.body.w = w
+		w.body.what = Body
 		w.tag.w = w
+		w.tag.what = Tag
 
 		wincol := &row.col
 		wincol.w = append(wincol.w, w)
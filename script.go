@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fhs/edward/internal/config"
+	"github.com/fhs/edward/internal/script"
+)
+
+// Scripts in the user's script directory define tag commands that run
+// in-process. A script defines a command by calling (command "Name" fn),
+// where fn takes the command's argument as a string. While the command
+// runs, the bindings defined in scriptinit operate on the window in which
+// it was executed.
+var scripts struct {
+	in   *script.Interp
+	cmds map[string]script.Value // tag commands defined by scripts
+	scriptctx
+}
+
+// scriptctx is the context of the script command being run.
+type scriptctx struct {
+	w      *Window
+	et     *Text
+	marked bool // body has been marked for undo
+}
+
+// scriptdir returns the directory from which scripts are loaded.
+func scriptdir() string {
+	dir, err := config.Dir()
+	if err != nil {
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "edward", "scripts")
+}
+
+// loadscripts creates a new interpreter and evaluates the scripts (files
+// with the suffix .scm) in dir in lexical order. Errors are reported in
+// the +Errors window.
+func loadscripts(dir string) {
+	scriptinit()
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			warning(nil, "can't read scripts: %v\n", err)
+		}
+		return
+	}
+	var names []string
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && strings.HasSuffix(fi.Name(), ".scm") {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := scripts.in.EvalFile(filepath.Join(dir, name)); err != nil {
+			warning(nil, "script %v\n", err)
+		}
+	}
+}
+
+// lookupscript returns the Exectab for a command defined by a script, or nil.
+func lookupscript(name string) *Exectab {
+	if _, ok := scripts.cmds[name]; !ok {
+		return nil
+	}
+	return &Exectab{
+		name: name,
+		fn: func(et, _, argt *Text, _, _ bool, arg string) {
+			runscript(name, et, argt, arg)
+		},
+	}
+}
+
+func runscript(name string, et, argt *Text, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	if r, _ := getarg(argt, false, true); r != "" {
+		arg = r
+	}
+	// A script may run another script command with exec.
+	saved := scripts.scriptctx
+	scripts.scriptctx = scriptctx{w: et.w, et: et}
+	defer func() { scripts.scriptctx = saved }()
+
+	seq++
+	if _, err := scripts.in.Call(scripts.cmds[name], arg); err != nil {
+		warning(nil, "%v: %v\n", name, err)
+	}
+	if w := et.w; w.col != nil {
+		w.body.ScrDraw(w.body.fr.GetFrameFillStatus().Nchars)
+		w.SetTag()
+	}
+}
+
+// scriptinit creates the interpreter and defines the functions scripts
+// use to operate on Edward.
+func scriptinit() {
+	in := script.New()
+	scripts.in = in
+	scripts.cmds = make(map[string]script.Value)
+
+	in.Define("command", script.Func(func(args []script.Value) (script.Value, error) {
+		if err := script.CheckArgs("command", args, 2); err != nil {
+			return nil, err
+		}
+		name, err := script.String("command", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return nil, fmt.Errorf("command: bad name %q", name)
+		}
+		scripts.cmds[name] = args[1]
+		return nil, nil
+	}))
+	in.Define("warn", script.Func(func(args []script.Value) (script.Value, error) {
+		s := make([]string, len(args))
+		for i, a := range args {
+			s[i] = script.Format(a)
+		}
+		warning(nil, "%s\n", strings.Join(s, " "))
+		return nil, nil
+	}))
+	in.Define("window-id", windowFunc("window-id", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		return w.id, nil
+	}))
+	in.Define("window-name", windowFunc("window-name", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		return w.body.file.name, nil
+	}))
+	in.Define("window-dir", windowFunc("window-dir", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		return w.body.AbsDirName(""), nil
+	}))
+	in.Define("body", windowFunc("body", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		t := &w.body
+		r := make([]rune, t.Nc())
+		t.file.b.Read(0, r)
+		return string(r), nil
+	}))
+	in.Define("body-length", windowFunc("body-length", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		return w.body.Nc(), nil
+	}))
+	in.Define("dot", windowFunc("dot", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		return []script.Value{w.body.q0, w.body.q1}, nil
+	}))
+	in.Define("selection", windowFunc("selection", 0, func(w *Window, args []script.Value) (script.Value, error) {
+		t := &w.body
+		r := make([]rune, t.q1-t.q0)
+		t.file.b.Read(t.q0, r)
+		return string(r), nil
+	}))
+	in.Define("select", windowFunc("select", 2, func(w *Window, args []script.Value) (script.Value, error) {
+		q0, q1, err := scriptrange("select", &w.body, args)
+		if err != nil {
+			return nil, err
+		}
+		w.body.Show(q0, q1, true)
+		return nil, nil
+	}))
+	in.Define("insert", windowFunc("insert", 2, func(w *Window, args []script.Value) (script.Value, error) {
+		q, err := script.Int("insert", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		s, err := script.String("insert", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		t := &w.body
+		if q < 0 || q > t.Nc() {
+			return nil, fmt.Errorf("insert: position %d out of range", q)
+		}
+		if t.file.ReadOnly() {
+			return nil, fmt.Errorf("insert: %v", ErrReadOnly)
+		}
+		scriptmark(w)
+		t.Insert(q, []rune(s), true)
+		return nil, nil
+	}))
+	in.Define("delete", windowFunc("delete", 2, func(w *Window, args []script.Value) (script.Value, error) {
+		q0, q1, err := scriptrange("delete", &w.body, args)
+		if err != nil {
+			return nil, err
+		}
+		if w.body.file.ReadOnly() {
+			return nil, fmt.Errorf("delete: %v", ErrReadOnly)
+		}
+		scriptmark(w)
+		w.body.Delete(q0, q1, true)
+		return nil, nil
+	}))
+	in.Define("edit", windowFunc("edit", 1, func(w *Window, args []script.Value) (script.Value, error) {
+		cmd, err := script.String("edit", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		editcmd(scripts.et, []rune(cmd))
+		return nil, nil
+	}))
+	in.Define("exec", windowFunc("exec", 1, func(w *Window, args []script.Value) (script.Value, error) {
+		cmd, err := script.String("exec", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		e := lookup(cmd)
+		if e == nil {
+			return nil, fmt.Errorf("exec: unknown command %q", cmd)
+		}
+		words := wsre.Split(strings.TrimLeft(cmd, " \t\n"), 2)
+		arg := ""
+		if len(words) > 1 {
+			arg = strings.TrimLeft(words[1], " \t\n")
+		}
+		if e.mark {
+			seq++
+			w.body.file.Mark(seq)
+		}
+		e.fn(scripts.et, &w.body, nil, e.flag1, e.flag2, arg)
+		return nil, nil
+	}))
+}
+
+// windowFunc returns a function taking n arguments that applies fn to the
+// window in which the script command is running.
+func windowFunc(name string, n int, fn func(w *Window, args []script.Value) (script.Value, error)) script.Func {
+	return func(args []script.Value) (script.Value, error) {
+		if err := script.CheckArgs(name, args, n); err != nil {
+			return nil, err
+		}
+		if scripts.w == nil || scripts.w.col == nil {
+			return nil, fmt.Errorf("%v: no window", name)
+		}
+		return fn(scripts.w, args)
+	}
+}
+
+// scriptrange returns the range in t given by the first two arguments.
+func scriptrange(name string, t *Text, args []script.Value) (q0, q1 int, err error) {
+	if q0, err = script.Int(name, args, 0); err != nil {
+		return 0, 0, err
+	}
+	if q1, err = script.Int(name, args, 1); err != nil {
+		return 0, 0, err
+	}
+	if q0 < 0 || q1 < q0 || q1 > t.Nc() {
+		return 0, 0, fmt.Errorf("%v: range %d,%d out of bounds", name, q0, q1)
+	}
+	return q0, q1, nil
+}
+
+// scriptmark marks the body of w for undo the first time
+// the running command changes it.
+func scriptmark(w *Window) {
+	if !scripts.marked {
+		w.body.file.Mark(seq)
+		scripts.marked = true
+	}
+	w.Commit(&w.body)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testscript = `
+; Commands used by TestScriptCommands.
+(command "Wrap" (lambda (arg)
+  (let ((d (dot)))
+    (insert (car (cdr d)) arg)
+    (insert (car d) arg))))
+(command "Sub" (lambda (arg) (edit (string-append ",s/" arg "/g"))))
+(command "Info" (lambda (arg) (warn (window-name) (body-length) (selection))))
+(command "Bad" (lambda (arg) (delete 0 1000)))
+(command "Twice" (lambda (arg) (exec "Wrap -") (exec "Wrap +")))
+`
+
+func TestScriptCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edward-scripts")
+	if err != nil {
+		t.Fatalf("can't make temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for name, s := range map[string]string{
+		"a.scm":     testscript,
+		"b.scm":     "(command \"Broken\"",
+		"notes.txt": "not a script",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644); err != nil {
+			t.Fatalf("can't write script: %v", err)
+		}
+	}
+	warnings = nil
+	cwarn = nil
+	loadscripts(dir)
+	if got, want := len(warnings), 1; got != want {
+		t.Fatalf("got %v warnings loading scripts; expected %v", got, want)
+	}
+	if got, want := string(warnings[0].buf), "script "+filepath.Join(dir, "b.scm")+": line 1: missing ')'\n"; got != want {
+		t.Errorf("got warning %q; expected %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		cmd      string
+		dot      Range
+		readonly bool
+		body     string
+		warnings string
+	}{
+		{"Wrap *", Range{0, 4}, false, "*This* is a\nshort text\nto try addressing\n", ""},
+		{"Twice", Range{5, 7}, false, "This +-is+- a\nshort text\nto try addressing\n", ""},
+		{"Sub short/long", Range{0, 0}, false, "This is a\nlong text\nto try addressing\n", ""},
+		{"Info", Range{10, 15}, false, contents, "/a/file 39 short\n"},
+		{"Bad", Range{0, 0}, false, contents, "Bad: delete: range 0,1000 out of bounds\n"},
+		{"Wrap *", Range{0, 4}, true, contents, "Wrap: insert: window is read-only\n"},
+	} {
+		warnings = nil
+		w := makeSkeletonWindowModel(tc.dot, "/a/file")
+		w.body.file.SetReadOnly(tc.readonly)
+		e := lookup(tc.cmd)
+		if e == nil {
+			t.Errorf("command %q not found", tc.cmd)
+			continue
+		}
+		arg := ""
+		if words := strings.SplitN(tc.cmd, " ", 2); len(words) > 1 {
+			arg = words[1]
+		}
+		e.fn(&w.tag, &w.body, nil, e.flag1, e.flag2, arg)
+
+		if got := string(w.body.file.b); got != tc.body {
+			t.Errorf("%v: body is %q; expected %q", tc.cmd, got, tc.body)
+		}
+		got := ""
+		for _, warn := range warnings {
+			got += string(warn.buf)
+		}
+		if got != tc.warnings {
+			t.Errorf("%v: warnings are %q; expected %q", tc.cmd, got, tc.warnings)
+		}
+	}
+	if e := lookup("Broken"); e != nil {
+		t.Errorf("found command defined by broken script")
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/fhs/edward/internal/regexp"
+)
+
+// Name of the window showing the results of Search, in the searched directory.
+const searchName = "+Search"
+
+// Searches writing to +Search windows, and walks of the files listed by
+// Find and Replace, by window. A search is stopped by closing its channel,
+// which is done by whoever removes it from searches.
+var (
+	searchmu sync.Mutex
+	searches = make(map[*Window]chan struct{})
+)
+
+// searchx implements the Search command, which searches the files in the
+// directory of the window for a regular expression. Files and directories
+// ignored by .gitignore files are skipped. The matching lines are listed
+// in a +Search window as path:line:col: text, ready to be opened by Look.
+// The regular expression is given as in Replace, as /regexp/ with any
+// punctuation for the slashes, or else is the selected text.
+func searchx(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	if et == nil || et.w == nil {
+		return
+	}
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		arg, _ = getarg(argt, false, false)
+	}
+	var pat string
+	if arg != "" {
+		cp := newCmdParser([]rune(arg))
+		delim := cp.getch()
+		if !okdelim(delim) {
+			warning(nil, "Search: bad delimiter %q\n", delim)
+			return
+		}
+		var err error
+		pat, err = cp.getregexp(delim)
+		if err != nil {
+			warning(nil, "Search: %v\n", err)
+			return
+		}
+	} else {
+		t := &et.w.body
+		r := make([]rune, t.q1-t.q0)
+		t.file.b.Read(t.q0, r)
+		pat = string(r)
+	}
+	if pat == "" {
+		warning(nil, "usage: Search /regexp/\n")
+		return
+	}
+	re, err := regexp.CompileAcme(pat)
+	if err != nil {
+		warning(nil, "Search: bad regexp %q: %v\n", pat, err)
+		return
+	}
+	dir := et.w.body.AbsDirName("")
+	if isremote(dir) {
+		warning(nil, "Search: can't search remote directory %v\n", dir)
+		return
+	}
+
+	name := filepath.Join(dir, searchName)
+	w := lookfile(name)
+	if w == nil {
+		w = row.col.Add(nil, -1)
+		defer w.HandleInput()
+		w.filemenu = false
+		w.SetName(name)
+		xfidlog(w, "new")
+	}
+	if w.body.file != et.w.body.file {
+		w.Lock('E')
+		defer w.Unlock()
+	}
+	stopsearch(w)
+	t := &w.body
+	t.Delete(0, t.Nc(), true)
+	t.file.Clean()
+	t.SetSelect(0, 0)
+	w.SetTag()
+
+	stop := make(chan struct{})
+	searchmu.Lock()
+	searches[w] = stop
+	searchmu.Unlock()
+	results := make(chan string)
+	go searchtree(dir, re, results, stop)
+	w.ref.Inc()
+	go w.searchappender(stop, results)
+}
+
+// stopsearch stops the search writing to w, if there is one.
+func stopsearch(w *Window) {
+	searchmu.Lock()
+	defer searchmu.Unlock()
+	if stop, ok := searches[w]; ok {
+		close(stop)
+		delete(searches, w)
+	}
+}
+
+// walkwindow runs walk in the background for the window w, which is
+// locked, stopping any search already writing to w. When walk returns,
+// done is called with the row and w locked, unless w was closed or the
+// walk stopped in the meantime. Walk should return soon after stop is
+// closed.
+func (w *Window) walkwindow(walk func(stop <-chan struct{}), done func()) {
+	stopsearch(w)
+	stop := make(chan struct{})
+	searchmu.Lock()
+	searches[w] = stop
+	searchmu.Unlock()
+	w.ref.Inc()
+	go func() {
+		walk(stop)
+		row.lk.Lock()
+		defer row.lk.Unlock()
+		w.Lock('F')
+		defer w.Unlock()
+		select {
+		case <-stop:
+		default:
+			if w.col != nil {
+				done()
+				if w.display != nil {
+					w.display.Flush()
+				}
+			}
+		}
+		searchmu.Lock()
+		if searches[w] == stop {
+			delete(searches, w)
+		}
+		searchmu.Unlock()
+		w.Close() // drop the reference taken above
+	}()
+}
+
+// searchappender appends the results of the search stopped by stop to
+// w, until there are no more or the search is stopped.
+func (w *Window) searchappender(stop chan struct{}, results <-chan string) {
+	for s := range results {
+		w.Lock('F')
+		select {
+		case <-stop:
+			w.Unlock()
+			continue // let searchtree finish
+		default:
+		}
+		if w.col == nil {
+			stopsearch(w)
+			w.Unlock()
+			continue
+		}
+		t := &w.body
+		t.Insert(t.Nc(), []rune(s), true)
+		t.file.Clean()
+		if t.fr != nil {
+			t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+		}
+		w.SetTag()
+		if w.display != nil {
+			w.display.Flush()
+		}
+		w.Unlock()
+	}
+	searchmu.Lock()
+	if searches[w] == stop {
+		delete(searches, w)
+	}
+	searchmu.Unlock()
+	w.Lock('F')
+	w.Close() // drop the reference taken by searchx
+	w.Unlock()
+}
+
+// searchtree searches the files under dir for re. The matches in each
+// file are sent to results as lines of the form path:line:col: text, with
+// the path relative to dir. It closes results when done, which is early
+// if stop is closed.
+func searchtree(dir string, re *regexp.Regexp, results chan<- string, stop <-chan struct{}) {
+	walktree(dir, stop, func(p string) {
+		s := searchfile(dir, p, re)
+		if s == "" {
+			return
+		}
+		select {
+		case results <- s:
+		case <-stop:
+		}
+	})
+	close(results)
+}
+
+// walktree calls fn, in parallel, for each regular file under dir that
+// isn't ignored by a .gitignore file or inside a .git directory. It
+// returns when all the calls are done, which is early if stop is closed.
+func walktree(dir string, stop <-chan struct{}, fn func(p string)) {
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+		ignores := map[string]*ignorer{
+			filepath.Dir(dir): parentignorer(dir),
+		}
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			ig := ignores[filepath.Dir(p)]
+			if p != dir && (info.Name() == ".git" || ig.ignored(p, info.IsDir())) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				ignores[p] = ig.load(p)
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			select {
+			case paths <- p:
+				return nil
+			case <-stop:
+				return fmt.Errorf("walk stopped")
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				fn(p)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// searchfile returns the lines of the file p that match re, in the form
+// described at searchtree.
+func searchfile(dir, p string, re *regexp.Regexp) string {
+	b, err := ioutil.ReadFile(p)
+	if err != nil || bytes.IndexByte(b, 0) >= 0 {
+		return ""
+	}
+	name, err := filepath.Rel(dir, p)
+	if err != nil {
+		name = p
+	}
+	name = filepath.ToSlash(name)
+	var sb strings.Builder
+	for n := 1; len(b) > 0; n++ {
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i], b[i+1:]
+		} else {
+			b = nil
+		}
+		if loc := re.FindIndex(line); loc != nil {
+			col := utf8.RuneCount(line[:loc[0]]) + 1
+			fmt.Fprintf(&sb, "%s:%d:%d: %s\n", name, n, col, line)
+		}
+	}
+	return sb.String()
+}
+
+// An ignorer holds the patterns of the .gitignore files that apply in a
+// directory. A nil *ignorer ignores nothing.
+type ignorer struct {
+	parent   *ignorer
+	dir      string // directory holding the .gitignore file
+	patterns []ignorepattern
+}
+
+type ignorepattern struct {
+	glob     string // slash-separated glob, which may contain **
+	negate   bool   // the pattern started with !
+	dironly  bool   // the pattern ended with /
+	anchored bool   // glob is matched against the path relative to dir, not the base name
+}
+
+// load returns the ignorer for the directory dir, inside the directory
+// of ig, adding the patterns of dir/.gitignore if there is one.
+func (ig *ignorer) load(dir string) *ignorer {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return ig
+	}
+	return &ignorer{
+		parent:   ig,
+		dir:      dir,
+		patterns: parseignore(string(b)),
+	}
+}
+
+// parentignorer returns the ignorer for the parent of dir, holding the
+// patterns of the .gitignore files from the root of the project
+// containing dir down to the parent.
+func parentignorer(dir string) *ignorer {
+	root := projectroot(dir)
+	if root == "" || root == dir {
+		return nil
+	}
+	var dirs []string
+	for d := filepath.Dir(dir); ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if d == root {
+			break
+		}
+	}
+	var ig *ignorer
+	for i := len(dirs) - 1; i >= 0; i-- {
+		ig = ig.load(dirs[i])
+	}
+	return ig
+}
+
+// parseignore parses the contents of a .gitignore file.
+func parseignore(s string) []ignorepattern {
+	var pats []ignorepattern
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var p ignorepattern
+		if line[0] == '!' {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // escaped ! or #
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dironly = true
+			line = strings.TrimRight(line, "/")
+		}
+		p.anchored = strings.Contains(line, "/")
+		p.glob = strings.TrimPrefix(line, "/")
+		if p.glob != "" {
+			pats = append(pats, p)
+		}
+	}
+	return pats
+}
+
+// ignored reports whether the file p is ignored. The last pattern that
+// matches decides, and the patterns of a directory's own .gitignore come
+// after those of its parents.
+func (ig *ignorer) ignored(p string, isdir bool) bool {
+	for ; ig != nil; ig = ig.parent {
+		rel, err := filepath.Rel(ig.dir, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for i := len(ig.patterns) - 1; i >= 0; i-- {
+			pat := &ig.patterns[i]
+			if pat.dironly && !isdir {
+				continue
+			}
+			name := rel
+			if !pat.anchored {
+				name = path.Base(rel)
+			}
+			if globmatch(strings.Split(pat.glob, "/"), strings.Split(name, "/")) {
+				return !pat.negate
+			}
+		}
+	}
+	return false
+}
+
+// globmatch reports whether the path elements name match the glob
+// elements pat, where a ** element matches any number of path elements.
+func globmatch(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if globmatch(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fhs/edward/internal/regexp"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGlobmatch(t *testing.T) {
+	for _, tc := range []struct {
+		pat, name string
+		match     bool
+	}{
+		{"a", "a", true},
+		{"a", "b", false},
+		{"*.o", "x.o", true},
+		{"*.o", "x.c", false},
+		{"a/*", "a/b", true},
+		{"a/*", "a/b/c", false},
+		{"**/b", "b", true},
+		{"**/b", "a/x/b", true},
+		{"a/**", "a/b/c", true},
+		{"a/**/c", "a/c", true},
+		{"a/**/c", "a/b/x/c", true},
+		{"a/**/c", "a/b/x/d", false},
+	} {
+		got := globmatch(strings.Split(tc.pat, "/"), strings.Split(tc.name, "/"))
+		if got != tc.match {
+			t.Errorf("globmatch(%q, %q) is %v; want %v", tc.pat, tc.name, got, tc.match)
+		}
+	}
+}
+
+func TestIgnorerIgnored(t *testing.T) {
+	root := &ignorer{
+		dir: "/p",
+		patterns: parseignore(`# comment
+*.o
+build/
+/top
+doc/*.html
+!keep.o
+\#hash
+`),
+	}
+	sub := &ignorer{
+		parent:   root,
+		dir:      "/p/sub",
+		patterns: parseignore("!*.o\nlocal\n"),
+	}
+	for _, tc := range []struct {
+		ig      *ignorer
+		path    string
+		isdir   bool
+		ignored bool
+	}{
+		{nil, "/p/x.o", false, false},
+		{root, "/p/x.o", false, true},
+		{root, "/p/a/b/x.o", false, true},
+		{root, "/p/keep.o", false, false},
+		{root, "/p/x.c", false, false},
+		{root, "/p/build", true, true},
+		{root, "/p/a/build", true, true},
+		{root, "/p/build", false, false},
+		{root, "/p/top", false, true},
+		{root, "/p/a/top", false, false},
+		{root, "/p/doc/x.html", false, true},
+		{root, "/p/a/doc/x.html", false, false},
+		{root, "/p/#hash", false, true},
+		{root, "/p/# comment", false, false},
+		{sub, "/p/sub/x.o", false, false},
+		{sub, "/p/sub/local", false, true},
+		{sub, "/p/local", false, false},
+		{sub, "/p/sub/build", true, true},
+	} {
+		if got := tc.ig.ignored(tc.path, tc.isdir); got != tc.ignored {
+			t.Errorf("ignored(%q, %v) is %v; want %v", tc.path, tc.isdir, got, tc.ignored)
+		}
+	}
+}
+
+func TestSearchtree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"go.mod":          "module example\n",
+		".gitignore":      "*.log\nvendor/\n",
+		"a.go":            "package a\n\nfunc Hello() {}\n",
+		"b.txt":           "say hello\nHELLO\n\tαβ hello",
+		"bin.dat":         "hello\x00world",
+		"x.log":           "hello\n",
+		"vendor/v.go":     "hello\n",
+		".git/config":     "hello\n",
+		"sub/.gitignore":  "!*.log\n",
+		"sub/y.log":       "hello there\n",
+		"sub/deep/z.go":   "// hello\n",
+		"sub/deep/z.log":  "hello\n",
+		"other/readme.md": "nothing to see\n",
+	}
+	for name, s := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(dir, pat string) []string {
+		results := make(chan string)
+		go searchtree(dir, regexp.MustCompile(pat), results, make(chan struct{}))
+		var lines []string
+		for s := range results {
+			lines = append(lines, strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")...)
+			lines[len(lines)-1] += "\n"
+		}
+		sort.Strings(lines)
+		return lines
+	}
+
+	got := search(dir, "(?i)hello")
+	want := []string{
+		"a.go:3:6: func Hello() {}\n",
+		"b.txt:1:5: say hello\n",
+		"b.txt:2:1: HELLO\n",
+		"b.txt:3:5: \tαβ hello\n",
+		"sub/deep/z.go:1:4: // hello\n",
+		"sub/deep/z.log:1:1: hello\n",
+		"sub/y.log:1:1: hello there\n",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("search of the project mismatch (-want +got):\n%s", diff)
+	}
+
+	// The .gitignore files above the directory searched still apply.
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "deep", "w.log"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", ".gitignore"), []byte("z.go\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got = search(filepath.Join(dir, "sub", "deep"), "hello")
+	if diff := cmp.Diff([]string(nil), got); diff != "" {
+		t.Errorf("search of a subdirectory mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchx(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("x /foo/ y\nfooo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		warningsMu.Lock()
+		warnings = []*Warning{}
+		warningsMu.Unlock()
+	}()
+
+	w := makeSkeletonWindowModel(Range{0, 0}, filepath.Join(dir, "file"))
+	sw := row.col.w[1]
+	sw.SetName(filepath.Join(dir, searchName))
+
+	for _, tc := range []struct {
+		arg  string
+		want string
+	}{
+		{"/fo+/", "a.txt:1:4: x /foo/ y\na.txt:2:1: fooo\n"},
+		{"  :/fo+:  ", "a.txt:1:3: x /foo/ y\n"},
+		{`/\/fo+/`, "a.txt:1:3: x /foo/ y\n"},
+		{"|o{3}", "a.txt:2:2: fooo\n"},
+	} {
+		searchx(&w.body, nil, nil, false, false, tc.arg)
+		var got string
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			sw.Lock('M')
+			got = string(sw.body.View(0, sw.body.Nc()))
+			sw.Unlock()
+			searchmu.Lock()
+			_, running := searches[sw]
+			searchmu.Unlock()
+			if !running || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got != tc.want {
+			t.Errorf("Search %v found %q; want %q", tc.arg, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fhs/edward/internal/config"
+)
+
+// session is the name of the current session. Its dump file is saved
+// when Edward exits. It's empty if there is no current session.
+var session string
+
+// sessiondir returns the directory holding the dump files of sessions.
+func sessiondir() string {
+	dir, err := config.Dir()
+	if err != nil {
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "edward", "sessions")
+}
+
+// sessionfile returns the dump file of the named session.
+func sessionfile(name string) string {
+	return filepath.Join(sessiondir(), name+".dump")
+}
+
+// projectroot returns the nearest directory containing dir that holds a
+// go.mod file or a .git directory, or "" if there is none.
+func projectroot(dir string) string {
+	for {
+		for _, marker := range []string{"go.mod", ".git"} {
+			if access(filepath.Join(dir, marker)) {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// projectsession returns the name of the session for the project
+// containing dir, or "" if dir is not in a project. The name is the base
+// name of the project root followed by a hash of its path, so that
+// projects with the same base name get different sessions.
+func projectsession(dir string) string {
+	root := projectroot(dir)
+	if root == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%.8x", filepath.Base(root), sha1.Sum([]byte(root)))
+}
+
+// sessionnames returns the names of the saved sessions in lexical order.
+func sessionnames() ([]string, error) {
+	fis, err := ioutil.ReadDir(sessiondir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if name := fi.Name(); strings.HasSuffix(name, ".dump") {
+			names = append(names, strings.TrimSuffix(name, ".dump"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// savesession saves the state of Edward as the named session.
+func savesession(name string) error {
+	if err := os.MkdirAll(sessiondir(), 0700); err != nil {
+		return warnError(nil, "can't save session: %v", err)
+	}
+	return row.Dump(sessionfile(name))
+}
+
+// autosave saves the state of Edward in the current session, or in the
+// default dump file if there is no current session.
+func autosave() {
+	if session != "" {
+		savesession(session)
+		return
+	}
+	row.Dump("")
+}
+
+func badsessionname(name string) bool {
+	return name == "" || name[0] == '.' || strings.ContainsAny(name, `/\`)
+}
+
+// sessionx implements the Session command:
+//
+//	Session save [name]	save the state as the named or current session
+//	Session open name	replace the windows with those of a session
+//	Session list		list the saved sessions
+func sessionx(et *Text, _ *Text, argt *Text, _, _ bool, arg string) {
+	args := strings.Fields(arg)
+	if r, _ := getarg(argt, false, false); r != "" {
+		args = append(args, r)
+	}
+	if len(args) == 0 {
+		warning(nil, "usage: Session save [name] | open name | list\n")
+		return
+	}
+	cmd, args := args[0], args[1:]
+	name := session
+	if len(args) > 0 {
+		name = args[0]
+	}
+	switch cmd {
+	case "save":
+		if name == "" {
+			warning(nil, "Session save: no current session\n")
+			return
+		}
+		if badsessionname(name) {
+			warning(nil, "Session: bad session name %q\n", name)
+			return
+		}
+		if savesession(name) == nil {
+			session = name
+		}
+
+	case "open":
+		if len(args) == 0 || badsessionname(name) {
+			warning(nil, "usage: Session open name\n")
+			return
+		}
+		if !access(sessionfile(name)) {
+			warning(nil, "Session: no session %q\n", name)
+			return
+		}
+		if !row.Clean() {
+			return
+		}
+		old := append([]*Window(nil), row.col.w...)
+		if err := row.Load(nil, sessionfile(name)); err != nil {
+			return
+		}
+		for _, w := range old {
+			if w.col != nil {
+				w.col.Close(w, true)
+			}
+		}
+		session = name
+
+	case "list":
+		names, err := sessionnames()
+		if err != nil {
+			warning(nil, "Session: %v\n", err)
+			return
+		}
+		var sb strings.Builder
+		for _, n := range names {
+			mark := " "
+			if n == session {
+				mark = "*"
+			}
+			fmt.Fprintf(&sb, "%s %s\n", mark, n)
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("no saved sessions\n")
+		}
+		warning(nil, "%s", sb.String())
+
+	default:
+		warning(nil, "Session: unknown subcommand %q\n", cmd)
+	}
+}
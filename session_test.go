@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestProjectroot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edward-project")
+	if err != nil {
+		t.Fatalf("can't make temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/.git", "a/b/c", "a/m/n"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatalf("can't make directory: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a/m/go.mod"), []byte("module m\n"), 0644); err != nil {
+		t.Fatalf("can't write go.mod: %v", err)
+	}
+
+	for _, tc := range []struct {
+		dir, root string
+	}{
+		{"a", "a"},
+		{"a/b/c", "a"},
+		{"a/m/n", "a/m"},
+	} {
+		if got, want := projectroot(filepath.Join(dir, tc.dir)), filepath.Join(dir, tc.root); got != want {
+			t.Errorf("project root of %v is %q; expected %q", tc.dir, got, want)
+		}
+	}
+	if got := projectroot(dir); got != "" && strings.HasPrefix(got, dir) {
+		t.Errorf("found project root %q for directory outside a project", got)
+	}
+
+	s1 := projectsession(filepath.Join(dir, "a/b"))
+	s2 := projectsession(filepath.Join(dir, "a/m"))
+	if !strings.HasPrefix(s1, "a-") || !strings.HasPrefix(s2, "m-") || badsessionname(s1) {
+		t.Errorf("bad project session names %q and %q", s1, s2)
+	}
+	if s := projectsession(filepath.Join(dir, "a/b/c")); s != s1 {
+		t.Errorf("directories in the same project have sessions %q and %q", s, s1)
+	}
+}
+
+func TestSessionCommand(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "windows", "plan9":
+		t.Skip("user configuration directory doesn't follow XDG_CONFIG_HOME")
+	}
+	dir, err := ioutil.TempDir("", "edward-sessions")
+	if err != nil {
+		t.Fatalf("can't make temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv("XDG_CONFIG_HOME", dir)()
+	defer func(s string) { session = s }(session)
+
+	configureGlobals()
+	cwarn = nil
+	session = ""
+	for _, tc := range []struct {
+		arg, warnings, session string
+	}{
+		{"", "usage: Session save [name] | open name | list\n", ""},
+		{"list", "no saved sessions\n", ""},
+		{"save", "Session save: no current session\n", ""},
+		{"save ../x", "Session: bad session name \"../x\"\n", ""},
+		{"save one", "", "one"},
+		{"save two", "", "two"},
+		{"list", "  one\n* two\n", "two"},
+		{"open three", "Session: no session \"three\"\n", "two"},
+		{"brew", "Session: unknown subcommand \"brew\"\n", "two"},
+	} {
+		warnings = nil
+		sessionx(nil, nil, nil, false, false, tc.arg)
+		got := ""
+		for _, warn := range warnings {
+			got += string(warn.buf)
+		}
+		if got != tc.warnings {
+			t.Errorf("Session %v: warnings are %q; expected %q", tc.arg, got, tc.warnings)
+		}
+		if session != tc.session {
+			t.Errorf("Session %v: current session is %q; expected %q", tc.arg, session, tc.session)
+		}
+	}
+	if !access(filepath.Join(dir, "edward", "sessions", "one.dump")) {
+		t.Errorf("session one not saved")
+	}
+}
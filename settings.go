@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fhs/edward/internal/config"
+	"github.com/fhs/edward/internal/draw"
+	"github.com/fhs/edward/internal/frame"
+)
+
+// cfg holds the settings read from the configuration file. Environment
+// variables and command line flags override them.
+var cfg = &config.Config{}
+
+// loadconfig reads the configuration file and applies the settings that
+// don't belong to a window. Errors are reported in the +Errors window and
+// leave the previous settings in effect.
+func loadconfig() {
+	filename, err := config.DefaultPath()
+	if err != nil {
+		filename = filepath.Join(home, ".config", "edward", "config")
+	}
+	if c, err := config.Load(filename); err != nil {
+		warning(nil, "can't load configuration: %v\n", err)
+	} else {
+		cfg = c
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if cfg.VarFont != "" && !set["f"] {
+		*varfontflag = cfg.VarFont
+	}
+	if cfg.FixedFont != "" && !set["F"] {
+		*fixedfontflag = cfg.FixedFont
+	}
+	acmeshell = cfg.Shell
+	if s := os.Getenv("acmeshell"); s != "" {
+		acmeshell = s
+	}
+	stop, expand := tabsfor("")
+	maxtab = uint(stop)
+	tabexpand = expand
+}
+
+// tabsfor returns the tab width and expansion for the file name.
+// The tabstop and tabexpand environment variables override the
+// configuration file.
+func tabsfor(name string) (stop int, expand bool) {
+	var t config.Tabs
+	if s := os.Getenv("tabstop"); s != "" {
+		n, _ := strconv.ParseInt(s, 10, 32)
+		t.Stop = int(n)
+	}
+	if s := os.Getenv("tabexpand"); s != "" {
+		b, _ := strconv.ParseBool(s)
+		t.Expand = &b
+	}
+	t = t.Merge(cfg.TabsFor(name))
+	stop = t.Stop
+	if stop <= 0 {
+		stop = 4
+	}
+	if t.Expand != nil {
+		expand = *t.Expand
+	}
+	return stop, expand
+}
+
+// setcolor sets *img to the colour given by the configuration setting
+// name, if there is one.
+func setcolor(display draw.Display, img *draw.Image, name string) {
+	c, ok := cfg.Colors[name]
+	if !ok {
+		return
+	}
+	i, err := display.AllocImage(image.Rect(0, 0, 1, 1), display.ScreenImage().Pix(), true, draw.Color(c))
+	if err != nil {
+		warning(nil, "can't allocate color %v: %v\n", name, err)
+		return
+	}
+	*img = i
+}
+
+// configcolors replaces the default tag and body colours in m with those
+// given in the configuration file.
+func configcolors(display draw.Display, m *iconImages) {
+	for i, name := range []string{"back", "high", "bord", "text", "htext"} {
+		setcolor(display, &m.tagcolors[frame.ColBack+i], "tag"+name)
+		setcolor(display, &m.textcolors[frame.ColBack+i], "body"+name)
+	}
+}
+
+// defaulttag returns the text following the bar in the tag of a new
+// window like w.
+func defaulttag(w *Window) string {
+	typ := "file"
+	switch {
+	case w.body.file.IsDir():
+		typ = "dir"
+	case strings.HasSuffix(w.body.file.name, "+Errors"):
+		typ = "errors"
+	case strings.HasSuffix(w.body.file.name, findName):
+		typ = "find"
+	}
+	if s, ok := cfg.Tags[typ]; ok {
+		return s
+	}
+	if typ == "find" {
+		return "Look Find"
+	}
+	return "Look Edit"
+}
+
+// reload rereads the configuration file and scripts, and applies the
+// settings to the open windows. Fonts only change in new windows.
+func reload(et *Text, _ *Text, _ *Text, _, _ bool, _ string) {
+	loadconfig()
+	loadscripts(scriptdir())
+	for _, w := range row.col.w {
+		w.body.tabstop, w.body.tabexpand = tabsfor(w.body.file.name)
+		if w.display == nil {
+			w.SetTag()
+			continue
+		}
+		w.tagcolors[frame.ColBack] = nil
+		iconinit(w.display, &w.iconImages, w.fontget)
+		w.tag.fr.Init(w.tag.fr.Rect(), frame.OptColors(w.tagcolors))
+		w.body.fr.Init(w.body.fr.Rect(), frame.OptColors(w.textcolors))
+		w.display.ScreenImage().Draw(w.r, w.textcolors[frame.ColBack], nil, image.Point{})
+		w.SetTag()
+		w.Resize(w.r, false, true)
+		w.display.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fhs/edward/internal/config"
+)
+
+// setenv sets the environment variable key to value, or unsets it if
+// value is empty, and returns a function restoring it.
+func setenv(key, value string) (restore func()) {
+	old, ok := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestTabsfor(t *testing.T) {
+	c, err := config.Parse(strings.NewReader("tabstop 2\next .go tabstop 8\next .py tabexpand true\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	defer func(c *config.Config) { cfg = c }(cfg)
+
+	for _, tc := range []struct {
+		cfg              *config.Config
+		tabstop, expandv string // environment
+		name             string
+		stop             int
+		expand           bool
+	}{
+		{&config.Config{}, "", "", "a.go", 4, false},
+		{c, "", "", "a.txt", 2, false},
+		{c, "", "", "a.go", 8, false},
+		{c, "", "", "a.py", 2, true},
+		{c, "6", "", "a.go", 6, false},
+		{c, "", "false", "a.py", 2, false},
+		{&config.Config{}, "3", "true", "a.c", 3, true},
+	} {
+		cfg = tc.cfg
+		defer setenv("tabstop", tc.tabstop)()
+		defer setenv("tabexpand", tc.expandv)()
+		stop, expand := tabsfor(tc.name)
+		if stop != tc.stop || expand != tc.expand {
+			t.Errorf("tabsfor(%q) with tabstop=%q tabexpand=%q is %v, %v; expected %v, %v",
+				tc.name, tc.tabstop, tc.expandv, stop, expand, tc.stop, tc.expand)
+		}
+	}
+}
+
+func TestDefaulttag(t *testing.T) {
+	c, err := config.Parse(strings.NewReader("tag dir Look Edit Find\ntag errors Clear\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	defer func(c *config.Config) { cfg = c }(cfg)
+	cfg = c
+
+	for _, tc := range []struct {
+		name  string
+		isdir bool
+		tag   string
+	}{
+		{"/a/file", false, "Look Edit"},
+		{"/a/", true, "Look Edit Find"},
+		{"/a/+Errors", false, "Clear"},
+		{"/a/+Find", false, "Look Find"},
+	} {
+		w := NewWindow().initHeadless(nil)
+		w.body.file.name = tc.name
+		w.body.file.isdir = tc.isdir
+		if got := defaulttag(w); got != tc.tag {
+			t.Errorf("default tag for %q is %q; expected %q", tc.name, got, tc.tag)
+		}
+	}
+}
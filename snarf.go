@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fhs/edward/internal/draw"
+	"github.com/fhs/edward/internal/runes"
+)
+
+// Snarf history. The most recently snarfed texts are kept in snarfs,
+// newest first; snarfs[0] is the text that was last put in the system
+// snarf buffer. It is guarded by row.lk.
+const maxsnarfs = 20
+
+var (
+	snarfs        [][]rune
+	snarfschanged bool // the +Snarfs window needs updating
+
+	// The entry being written to the snarf file, and the offset at which
+	// the next write continues it.
+	snarfwrite    []rune
+	snarfwriteoff uint64
+)
+
+// Name of the window listing the snarf history.
+const snarfsName = "+Snarfs"
+
+// addsnarf makes r the newest entry of the snarf history, removing any
+// older copy of it.
+func addsnarf(r []rune) {
+	if len(r) == 0 || (len(snarfs) > 0 && runes.Equal(snarfs[0], r)) {
+		return
+	}
+	for i, s := range snarfs {
+		if runes.Equal(s, r) {
+			snarfs = append(snarfs[:i], snarfs[i+1:]...)
+			break
+		}
+	}
+	snarfs = append([][]rune{r}, snarfs...)
+	if len(snarfs) > maxsnarfs {
+		snarfs = snarfs[:maxsnarfs]
+	}
+	snarfschanged = true
+}
+
+func acmeputsnarf(display draw.Display, snarf Buffer) {
+	r := make([]rune, snarf.nc())
+	snarf.Read(0, r[:snarf.nc()])
+	addsnarf(r)
+	display.WriteSnarf([]byte(string(r)))
+}
+
+// acmegetsnarf returns the contents of the system snarf buffer, whatever
+// its size. Text snarfed by other programs is added to the history.
+func acmegetsnarf(display draw.Display) Buffer {
+	b := make([]byte, 64*1024)
+	for {
+		n, size, err := display.ReadSnarf(b)
+		if size > len(b) {
+			b = make([]byte, size)
+			continue
+		}
+		if err != nil {
+			n = 0
+		}
+		b = b[:n]
+		break
+	}
+	r, _, _ := cvttorunes(b, len(b))
+	addsnarf(r)
+	snarf := NewBuffer()
+	snarf.Insert(0, r)
+	return snarf
+}
+
+// usesnarf makes entry i of the snarf history the current snarf.
+func usesnarf(display draw.Display, i int) error {
+	if i < 0 || i >= len(snarfs) {
+		return fmt.Errorf("no snarf %d", i)
+	}
+	r := snarfs[i]
+	addsnarf(r)
+	return display.WriteSnarf([]byte(string(r)))
+}
+
+// snarfsfile returns the contents of the snarf file: for each entry of the
+// snarf history, its number and length in bytes on a line, followed by
+// its text. Writes to the file take plain text instead; see
+// writesnarfsfile.
+func snarfsfile() string {
+	var sb strings.Builder
+	for i, r := range snarfs {
+		s := string(r)
+		fmt.Fprintf(&sb, "%d %d\n%s", i, len(s), s)
+	}
+	return sb.String()
+}
+
+// writesnarfsfile handles a write of n bytes at offset off to the snarf
+// file, holding the runes r. The file is written as plain text, not in
+// the numbered form it is read in. A write at offset 0 starts a new entry
+// of the history and makes it the current snarf. A write at a later
+// offset must continue the entry written last, and extends it.
+func writesnarfsfile(off uint64, n int, r []rune) error {
+	if len(row.col.w) == 0 {
+		return fmt.Errorf("no display for snarf")
+	}
+	if off == 0 {
+		snarfwrite = nil
+	} else if off != snarfwriteoff {
+		return fmt.Errorf("snarf write at offset %d doesn't continue an entry", off)
+	} else if len(snarfs) > 0 && runes.Equal(snarfs[0], snarfwrite) {
+		snarfs = snarfs[1:]
+	}
+	snarfwrite = append(snarfwrite, r...)
+	snarfwriteoff = off + uint64(n)
+	addsnarf(snarfwrite)
+	return row.col.w[0].display.WriteSnarf([]byte(string(snarfwrite)))
+}
+
+// snarfpreview returns the line listing entry i of the snarf history in
+// the +Snarfs window.
+func snarfpreview(i int, r []rune) string {
+	const max = 60
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\t", i)
+	for j, c := range r {
+		if j == max {
+			sb.WriteString("…")
+			break
+		}
+		switch c {
+		case '\n':
+			sb.WriteString("⏎")
+		case '\t':
+			sb.WriteString("→")
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// snarfsx implements the Snarfs command, which opens a window listing the
+// snarf history. Clicking button 3 on an entry makes it the current snarf.
+func snarfsx(et *Text, _ *Text, _ *Text, _, _ bool, _ string) {
+	if lookfile(snarfsName) == nil {
+		w := row.col.Add(nil, -1)
+		defer w.HandleInput()
+		w.filemenu = false
+		w.SetName(snarfsName)
+		xfidlog(w, "new")
+	}
+	snarfschanged = true
+}
+
+// flushsnarfs updates the +Snarfs window, if there is one, after the
+// snarf history has changed. The window is updated here, rather than
+// when the history changes, because the window may be the one being
+// snarfed from. Called with row.lk held.
+func flushsnarfs() {
+	if !snarfschanged {
+		return
+	}
+	snarfschanged = false
+	w := lookfile(snarfsName)
+	if w == nil {
+		return
+	}
+	w.Lock('F')
+	defer w.Unlock()
+	if w.col == nil {
+		return
+	}
+	var sb strings.Builder
+	for i, r := range snarfs {
+		sb.WriteString(snarfpreview(i, r))
+	}
+	t := &w.body
+	t.Delete(0, t.Nc(), true)
+	t.Insert(0, []rune(sb.String()), true)
+	t.file.Clean()
+	t.SetSelect(0, 0)
+	if t.fr != nil {
+		t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
+	}
+	w.SetTag()
+}
+
+// looksnarf handles a button 3 click at q in the +Snarfs window. It returns
+// whether the click selected an entry.
+func looksnarf(t *Text, q int) bool {
+	if t.what != Body || t.w == nil || t.file.name != snarfsName {
+		return false
+	}
+	q0 := q
+	for q0 > 0 && t.ReadC(q0-1) != '\n' {
+		q0--
+	}
+	var digits []rune
+	for q := q0; q < t.Nc(); q++ {
+		c := t.ReadC(q)
+		if c < '0' || c > '9' {
+			break
+		}
+		digits = append(digits, c)
+	}
+	i, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return false
+	}
+	if err := usesnarf(t.w.display, i); err != nil {
+		warning(nil, "Snarfs: %v\n", err)
+	}
+	return true
+}
+
+// The PRIMARY selection is set by an external program, because devdraw
+// only knows about the clipboard. Selections made with button 1 are sent
+// to primaryc, which holds at most the latest one.
+var primaryc chan string
+
+// startprimary starts mirroring selections to the PRIMARY selection, if a
+// program that sets it can be found.
+func startprimary() {
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-copy", "--primary"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		cmds = append(cmds,
+			[]string{"xclip", "-selection", "primary", "-i"},
+			[]string{"xsel", "--primary", "--input"})
+	}
+	var args []string
+	for _, a := range cmds {
+		if _, err := exec.LookPath(a[0]); err == nil {
+			args = a
+			break
+		}
+	}
+	if args == nil {
+		warning(nil, "can't set the PRIMARY selection: no wl-copy, xclip or xsel\n")
+		return
+	}
+	primaryc = make(chan string, 1)
+	go func() {
+		for s := range primaryc {
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Stdin = strings.NewReader(s)
+			cmd.Run()
+		}
+	}()
+}
+
+// setprimary mirrors the selection in t into the PRIMARY selection.
+func setprimary(t *Text) {
+	if primaryc == nil || t.q0 == t.q1 {
+		return
+	}
+	r := make([]rune, t.q1-t.q0)
+	t.file.b.Read(t.q0, r)
+	select {
+	case <-primaryc:
+	default:
+	}
+	primaryc <- string(r)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"9fans.net/go/plan9"
+	"github.com/fhs/edward/internal/edwoodtest"
+)
+
+func snarfstrings() []string {
+	var s []string
+	for _, r := range snarfs {
+		s = append(s, string(r))
+	}
+	return s
+}
+
+func TestAddsnarf(t *testing.T) {
+	snarfs = nil
+	for i := 0; i < maxsnarfs+5; i++ {
+		addsnarf([]rune(fmt.Sprint(i)))
+	}
+	if got := len(snarfs); got != maxsnarfs {
+		t.Errorf("history has %v entries; expected %v", got, maxsnarfs)
+	}
+	addsnarf([]rune("10"))
+	addsnarf(nil)
+	got := snarfstrings()
+	if got[0] != "10" || got[1] != fmt.Sprint(maxsnarfs+4) || len(got) != maxsnarfs {
+		t.Errorf("bad history after adding an old entry again: %q", got)
+	}
+	for _, s := range got[1:] {
+		if s == "10" {
+			t.Errorf("old copy of entry kept in history: %q", got)
+		}
+	}
+}
+
+func TestAcmegetsnarfLarge(t *testing.T) {
+	snarfs = nil
+	display := edwoodtest.NewDisplay()
+	big := strings.Repeat("0123456789abcdef", 16*1024) + "end" // more than 256 KiB
+
+	b := NewBuffer()
+	b.Insert(0, []rune(big))
+	acmeputsnarf(display, b)
+	got := acmegetsnarf(display)
+	r := make([]rune, got.nc())
+	got.Read(0, r)
+	if string(r) != big {
+		t.Errorf("got snarf of %v runes; expected %v", len(r), len(big))
+	}
+	if len(snarfs) != 1 {
+		t.Errorf("history has %v entries; expected 1", len(snarfs))
+	}
+}
+
+func TestSnarfFile(t *testing.T) {
+	snarfs = nil
+	configureGlobals()
+	display := edwoodtest.NewDisplay()
+	row.col.w = []*Window{{display: display}}
+
+	f := &Fid{qid: plan9.Qid{Path: QID(0, Qsnarf)}}
+	for _, w := range []struct {
+		off  uint64
+		data string
+	}{
+		{0, "first"},
+		{0, "sec"},
+		{3, "ond \xce"}, // α split between writes
+		{8, "\xb1\n"},
+	} {
+		mr := new(mockResponder)
+		xfidwrite(&Xfid{
+			fcall: plan9.Fcall{Offset: w.off, Data: []byte(w.data), Count: uint32(len(w.data))},
+			f:     f,
+			fs:    mr,
+		})
+		if mr.err != nil {
+			t.Fatalf("write of %q failed: %v", w.data, mr.err)
+		}
+	}
+	mr := new(mockResponder)
+	xfidread(&Xfid{
+		fcall: plan9.Fcall{Count: 1000},
+		f:     &Fid{qid: plan9.Qid{Path: QID(0, Qsnarf)}},
+		fs:    mr,
+	})
+	if got, want := string(mr.fcall.Data), "0 10\nsecond α\n1 5\nfirst"; got != want {
+		t.Errorf("snarf file is %q; expected %q", got, want)
+	}
+	b := make([]byte, 100)
+	n, _, _ := display.ReadSnarf(b)
+	if got, want := string(b[:n]), "second α\n"; got != want {
+		t.Errorf("system snarf buffer is %q; expected %q", got, want)
+	}
+}
+
+func TestSnarfFileWriteOffset(t *testing.T) {
+	snarfs = nil
+	configureGlobals()
+	display := edwoodtest.NewDisplay()
+	row.col.w = []*Window{{display: display}}
+
+	f := &Fid{qid: plan9.Qid{Path: QID(0, Qsnarf)}}
+	for _, w := range []struct {
+		off  uint64
+		data string
+		ok   bool
+	}{
+		{3, "gap", false}, // nothing to continue
+		{0, "abc", true},
+		{5, "gap", false}, // leaves a gap
+		{2, "xyz", false}, // overwrites
+		{3, "def", true},
+		{0, "ghi", true}, // a new entry, not a rewrite of the last one
+	} {
+		mr := new(mockResponder)
+		xfidwrite(&Xfid{
+			fcall: plan9.Fcall{Offset: w.off, Data: []byte(w.data), Count: uint32(len(w.data))},
+			f:     f,
+			fs:    mr,
+		})
+		if ok := mr.err == nil; ok != w.ok {
+			t.Errorf("write of %q at offset %v returned error %v", w.data, w.off, mr.err)
+		}
+	}
+	if got, want := snarfstrings(), []string{"ghi", "abcdef"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history is %q; expected %q", got, want)
+	}
+}
+
+func TestSnarfpreview(t *testing.T) {
+	for _, tc := range []struct {
+		r    string
+		line string
+	}{
+		{"hello", "3\thello\n"},
+		{"a\tb\nc", "3\ta→b⏎c\n"},
+		{strings.Repeat("x", 70), "3\t" + strings.Repeat("x", 60) + "…\n"},
+	} {
+		if got := snarfpreview(3, []rune(tc.r)); got != tc.line {
+			t.Errorf("preview of %q is %q; expected %q", tc.r, got, tc.line)
+		}
+	}
+}
+
+func TestLooksnarf(t *testing.T) {
+	snarfs = [][]rune{[]rune("zero"), []rune("one\ntwo")}
+	display := edwoodtest.NewDisplay()
+	w := &Window{display: display}
+	body := "0\tzero\n1\tone⏎two\n"
+	w.body = Text{
+		w:    w,
+		what: Body,
+		file: &File{name: snarfsName, b: Buffer([]rune(body))},
+	}
+	if !looksnarf(&w.body, len([]rune(body))-3) {
+		t.Fatalf("click on entry 1 not handled")
+	}
+	if got := snarfstrings(); got[0] != "one\ntwo" || got[1] != "zero" {
+		t.Errorf("history is %q after choosing entry 1", got)
+	}
+	w.body.file.name = "/a/file"
+	if looksnarf(&w.body, 0) {
+		t.Errorf("click in another window handled")
+	}
+}
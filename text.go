@@ -12,7 +12,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/fhs/edward/internal/complete"
 	"github.com/fhs/edward/internal/draw"
 	"github.com/fhs/edward/internal/draw/drawutil"
 	"github.com/fhs/edward/internal/frame"
@@ -148,7 +147,7 @@ func (t *Text) Redraw(r image.Rectangle, odx int, noredraw bool) {
 		t.fr.Redraw(enclosing)
 	}
 
-	if t.what == Body && t.file.IsDir() && odx != t.all.Dx() {
+	if t.what == Body && t.file.IsDir() && t.w.tree == nil && odx != t.all.Dx() {
 		if t.fr.GetFrameFillStatus().Maxlines > 0 {
 			t.Reset()
 			t.Columnate(t.w.dirnames, t.w.widths)
@@ -310,48 +309,76 @@ func (t *Text) Load(q0 int, filename string, setqid bool) (nread int, err error)
 	if err := t.checkSafeToLoad(filename); err != nil {
 		return 0, err
 	}
+	if p, host, file, ok := splitremote(filename); ok {
+		data, names, isdir, err := p.Read(host, file)
+		if err != nil {
+			return 0, warnError(nil, "can't open %s: %v", filename, err)
+		}
+		if isdir {
+			return t.loadDir(q0, filename, names)
+		}
+		return t.loadReader(q0, filename, bytes.NewReader(data), setqid && q0 == 0)
+	}
 	fd, err := os.Open(filename)
 	if err != nil {
 		return 0, warnError(nil, "can't open %s: %v", filename, err)
 	}
-	defer fd.Close()
 	d, err := fd.Stat()
 	if err != nil {
+		fd.Close()
 		return 0, warnError(nil, "can't fstat %s: %v", filename, err)
 	}
 	if setqid {
 		t.file.info = d
+		t.file.SetReadOnly(!d.IsDir() && !writable(d))
+	}
+	if !d.IsDir() && q0 == 0 && t.file.Size() == 0 && d.Size() > lazythreshold() {
+		return t.loadLazy(filename, fd, d.Size(), setqid)
 	}
+	defer fd.Close()
 
 	if d.IsDir() {
-		// this is checked in get() but it's possible the file changed underfoot
-		if t.file.HasMultipleTexts() {
-			return 0, warnError(nil, "%s is a directory; can't read with multiple windows on it", filename)
-		}
-		t.file.SetDir(true)
-		t.w.filemenu = false
-		if len(t.file.name) > 0 && !strings.HasSuffix(t.file.name, string(filepath.Separator)) {
-			t.file.name = t.file.name + string(filepath.Separator)
-			t.w.SetName(t.file.name)
-		}
 		dirNames, err := getDirNames(fd)
 		if err != nil {
 			return 0, warnError(nil, "getDirNames failed: %v", err)
 		}
-		widths := make([]int, len(dirNames))
-		dft := t.getfont()
-		for i, s := range dirNames {
-			widths[i] = dft.StringWidth(s)
-		}
-		t.Columnate(dirNames, widths)
-		t.w.dirnames = dirNames
-		t.w.widths = widths
-		q1 := t.file.Size()
-		return q1 - q0, nil
+		return t.loadDir(q0, filename, dirNames)
 	}
 	return t.loadReader(q0, filename, fd, setqid && q0 == 0)
 }
 
+// loadDir loads the listing of the directory filename, containing the
+// files dirNames, into the Text.file.
+func (t *Text) loadDir(q0 int, filename string, dirNames []string) (nread int, err error) {
+	// this is checked in get() but it's possible the file changed underfoot
+	if t.file.HasMultipleTexts() {
+		return 0, warnError(nil, "%s is a directory; can't read with multiple windows on it", filename)
+	}
+	t.file.SetDir(true)
+	t.w.filemenu = false
+	sep := string(filepath.Separator)
+	if isremote(t.file.name) {
+		sep = "/"
+	}
+	if len(t.file.name) > 0 && !strings.HasSuffix(t.file.name, sep) {
+		t.file.name = t.file.name + sep
+		t.w.SetName(t.file.name)
+	}
+	if t.w.tree != nil {
+		return t.loadTree(q0, t.file.name, dirNames)
+	}
+	widths := make([]int, len(dirNames))
+	dft := t.getfont()
+	for i, s := range dirNames {
+		widths[i] = dft.StringWidth(s)
+	}
+	t.Columnate(dirNames, widths)
+	t.w.dirnames = dirNames
+	t.w.widths = widths
+	q1 := t.file.Size()
+	return q1 - q0, nil
+}
+
 func getDirNames(f *os.File) ([]string, error) {
 	entries, err := f.Readdir(0)
 	if err != nil {
@@ -483,6 +510,16 @@ func (t *Text) Insert(q0 int, r []rune, tofile bool) {
 	t.file.InsertAt(q0, r)
 }
 
+// readonly reports whether t is the body of a window on a read-only file,
+// warning that it can't be changed if so.
+func (t *Text) readonly() bool {
+	if t.what == Body && t.file.ReadOnly() {
+		warning(nil, "%s is read-only\n", t.file.name)
+		return true
+	}
+	return false
+}
+
 func (t *Text) TypeCommit() {
 	if t.w != nil {
 		t.w.Commit(t)
@@ -674,57 +711,6 @@ func (t *Text) FileWidth(q0 int, oneelement bool) int {
 	return q0 - q
 }
 
-func (t *Text) Complete() []rune {
-	if t.q0 < t.Nc() && t.file.ReadC(t.q0) > ' ' { // must be at end of word
-		return nil
-	}
-	str := make([]rune, t.FileWidth(t.q0, true))
-	q := t.q0 - len(str)
-	for i := range str {
-		str[i] = t.file.ReadC(q)
-		q++
-	}
-	path := make([]rune, t.FileWidth(t.q0-len(str), false))
-	q = t.q0 - len(str) - len(path)
-	for i := range path {
-		path[i] = t.file.ReadC(q)
-		q++
-	}
-
-	// is path rooted? if not, we need to make it relative to window path
-	dir := string(path)
-	if !filepath.IsAbs(dir) {
-		dir = t.DirName("")
-		if len(dir) == 0 {
-			dir = Ldot
-		}
-		dir = filepath.Clean(filepath.Join(dir, string(path)))
-	}
-
-	c, err := complete.Complete(dir, string(str))
-	if err != nil {
-		warning(nil, "error attempting completion: %v\n", err)
-		return nil
-	}
-	if c.Advance {
-		return []rune(c.String)
-	}
-	var b bytes.Buffer
-	b.WriteString(dir)
-	if len(dir) > 0 && dir[len(dir)-1] != filepath.Separator {
-		b.WriteRune(filepath.Separator)
-	}
-	b.WriteString(string(str) + "*")
-	if c.NMatch == 0 {
-		b.WriteString(": no matches in:")
-	}
-	warning(nil, "%s\n", b.String())
-	for _, fn := range c.Filename {
-		warning(nil, " %s\n", fn)
-	}
-	return nil
-}
-
 func (t *Text) Type(r rune) {
 	var (
 		q0, q1    int
@@ -735,6 +721,12 @@ func (t *Text) Type(r rune) {
 	if t.what != Body && t.what != Tag && r == '\n' {
 		return
 	}
+	if t.w != nil && t.w.isearchkey(r) {
+		return
+	}
+	if t.w != nil && t.w.completekey(t, r) {
+		return
+	}
 	if t.what == Tag {
 		t.w.tagsafe = false
 	}
@@ -895,6 +887,9 @@ func (t *Text) Type(r rune) {
 		return
 
 	}
+	if t.readonly() {
+		return
+	}
 	if t.what == Body {
 		seq++
 		t.file.Mark(seq)
@@ -935,13 +930,8 @@ func (t *Text) Type(r rune) {
 	case 0x06:
 		fallthrough // ^F: complete
 	case draw.KeyInsert:
-		t.TypeCommit()
-		rp = t.Complete()
-		if rp == nil {
-			return
-		}
-		nr = len(rp) // runestrlen(rp);
-		// break into normal insertion case
+		t.startcomplete()
+		return
 	case 0x1B:
 		if t.eq0 != ^0 {
 			if t.eq0 <= t.q0 {
@@ -1072,6 +1062,8 @@ func (t *Text) Select() {
 	// log.Println("Text.Select Begin")
 	// defer log.Println("Text.Select End")
 
+	t.w.endcomplete() // the list is in the way
+
 	const (
 		None = iota
 		Cut
@@ -1177,6 +1169,7 @@ func (t *Text) Select() {
 		}
 		clicktext = nil
 	}
+	setprimary(t)
 }
 
 func (t *Text) Show(q0, q1 int, doselect bool) {
@@ -1588,7 +1581,7 @@ func (t *Text) Reset() {
 }
 
 func (t *Text) dirName(name string) string {
-	if t == nil || t.w == nil || filepath.IsAbs(name) {
+	if t == nil || t.w == nil || filepath.IsAbs(name) || isremote(name) {
 		return name
 	}
 	nt := t.w.tag.file.Size()
@@ -1596,6 +1589,9 @@ func (t *Text) dirName(name string) string {
 		return name
 	}
 	spl := t.w.ParseTag()
+	if isremote(spl) {
+		return joinremote(spl, name)
+	}
 	if !strings.HasSuffix(spl, string(filepath.Separator)) {
 		spl = filepath.Dir(spl)
 	}
@@ -1606,12 +1602,19 @@ func (t *Text) dirName(name string) string {
 // The filename name is appended to the result.
 // The returned path is guaranteed to be cleaned, as specified by filepath.Clean.
 func (t *Text) DirName(name string) string {
-	return filepath.Clean(t.dirName(name))
+	d := t.dirName(name)
+	if isremote(d) {
+		return d
+	}
+	return filepath.Clean(d)
 }
 
 // AbsDirName is the same as DirName but always returns an absolute path.
 func (t *Text) AbsDirName(name string) string {
 	d := t.dirName(name)
+	if isremote(d) {
+		return d
+	}
 	if !filepath.IsAbs(d) {
 		return filepath.Join(wdir, d)
 	}
@@ -30,7 +30,9 @@ func TestLoadReader(t *testing.T) {
 		in, out string
 	}{
 		{"temporary file's content\n", "temporary file's content\n"},
-		{"temporary file's \x00content\n", "temporary file's content\n"},
+		{"temporary file's \x00content\n", // binary files are shown as a hex dump
+			"00000000: 74 65 6d 70 6f 72 61 72  79 20 66 69 6c 65 27 73  |temporary file's|\n" +
+				"00000010: 20 00 63 6f 6e 74 65 6e  74 0a                    | .content.|\n"},
 	} {
 		text := emptyText()
 		_, err := text.LoadReader(0, "/home/gopher/test/main.go", strings.NewReader(tc.in), true)
@@ -55,7 +57,11 @@ func TestLoad(t *testing.T) {
 		in, out string
 	}{
 		{"temporary file's content\n", "temporary file's content\n"},
-		{"temporary file's \x00content\n", "temporary file's content\n"},
+		{"temporary file's \x00content\n", // binary files are shown as a hex dump
+			"00000000: 74 65 6d 70 6f 72 61 72  79 20 66 69 6c 65 27 73  |temporary file's|\n" +
+				"00000010: 20 00 63 6f 6e 74 65 6e  74 0a                    | .content.|\n"},
+		{"dos\r\nfile\r\n", "dos\nfile\n"},
+		{"\xFF\xFEh\x00i\x00\n\x00", "hi\n"},
 	} {
 		text := emptyText()
 		filename := filepath.Join(dir, "tmpfile")
@@ -74,6 +80,46 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edwood.test")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		perm     os.FileMode
+		readonly bool
+	}{
+		{0644, false},
+		{0444, os.Getuid() != 0}, // root can write anything
+	} {
+		filename := filepath.Join(dir, "tmpfile")
+		os.Remove(filename)
+		if err = ioutil.WriteFile(filename, []byte("content\n"), tc.perm); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		text := emptyText()
+		if _, err = text.Load(0, filename, true); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if got := text.file.ReadOnly(); got != tc.readonly {
+			t.Errorf("file with mode %v is read-only: %v; expected %v", tc.perm, got, tc.readonly)
+		}
+	}
+}
+
+func TestTextTypeReadOnly(t *testing.T) {
+	configureGlobals()
+	text := emptyText()
+	text.what = Body
+	text.file.SetReadOnly(true)
+	text.Type('a')
+	if got := string(text.file.cache); got != "" {
+		t.Errorf("typing into read-only body inserted %q", got)
+	}
+}
+
 func TestLoadError(t *testing.T) {
 	text := emptyText()
 	wantErr := "can't open /non-existent-filename:"
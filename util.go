@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/fhs/edward/internal/runes"
@@ -103,6 +104,7 @@ func errorwin1(dir string, incl []string) *Window {
 		w = row.col.Add(nil, -1)
 		defer w.HandleInput()
 		w.filemenu = false
+		w.ansi = &ansiFilter{}
 		w.SetName(r)
 		xfidlog(w, "new")
 	}
@@ -144,7 +146,7 @@ func errorwinforwin(w *Window) *Window {
 	)
 
 	t = &w.body
-	dir := t.DirName("")
+	dir := localdir(t.DirName(""))
 	incl = append(incl, w.incl...)
 	owner = w.owner
 	w.Unlock()
@@ -165,20 +167,68 @@ func makenewwindow(t *Text) *Window {
 }
 
 type Warning struct {
-	md  *MntDir
-	buf Buffer
+	md         *MntDir
+	buf        Buffer
+	nlines     int // number of newlines in buf
+	suppressed int // lines dropped from the head of buf
+}
+
+// trim drops lines from the head of the buffered text so that
+// at most n lines remain.
+func (warn *Warning) trim(n int) {
+	b := warn.buf
+	q, nl := taillines(b.nc(), n, func(p int) rune { return b[p] })
+	if q > 0 {
+		warn.buf.Delete(0, q)
+		warn.nlines -= nl
+		warn.suppressed += nl
+	}
 }
 
 var warnings = []*Warning{}
 var warningsMu sync.Mutex
 
+// warningInterval is the minimum time between insertions of warnings into
+// +Errors windows. Writes arriving in the meantime are coalesced, so that
+// a command flooding cons costs one redraw per interval instead of one per
+// write.
+const warningInterval = 50 * time.Millisecond
+
+var (
+	lastflush    time.Time // guarded by row.lk
+	flushpending bool      // a delayed flush is scheduled; guarded by warningsMu
+)
+
 func flushwarnings() {
 	var (
-		w                *Window
-		t                *Text
-		owner, nr, q0, n int
+		w         *Window
+		t         *Text
+		owner, q0 int
 	)
-	for _, warn := range warnings {
+	warningsMu.Lock()
+	if len(warnings) == 0 {
+		warningsMu.Unlock()
+		return
+	}
+	if d := time.Since(lastflush); d < warningInterval {
+		if !flushpending {
+			flushpending = true
+			time.AfterFunc(warningInterval-d, func() {
+				warningsMu.Lock()
+				flushpending = false
+				warningsMu.Unlock()
+				cwarn <- 0
+			})
+		}
+		warningsMu.Unlock()
+		return
+	}
+	ws := warnings
+	warnings = nil
+	warningsMu.Unlock()
+	lastflush = time.Now()
+
+	for _, warn := range ws {
 		w = errorwin(warn.md, 'E')
 		t = &w.body
 		owner = w.owner
@@ -186,24 +236,7 @@ func flushwarnings() {
 			w.owner = 'E'
 		}
 		w.Commit(t) // marks the backing text as dirty
-
-		// Most commands don't generate much output. For instance,
-		// Edit ,>cat goes through /dev/cons and is already in blocks
-		// because of the i/o system, but a few can.  Edit ,p will
-		// put the entire result into a single hunk.  So it's worth doing
-		// this in blocks (and putting the text in a buffer in the first
-		// place), to avoid a big memory footprint.
-		q0 = t.Nc()
-		r := make([]rune, RBUFSIZE)
-		// TODO(rjk): Figure out why Warning doesn't use a File.
-		for n = 0; n < warn.buf.nc(); n += nr {
-			nr = warn.buf.nc() - n
-			if nr > RBUFSIZE {
-				nr = RBUFSIZE
-			}
-			warn.buf.Read(n, r[:nr])
-			_, nr = t.BsInsert(t.Nc(), r[:nr], true)
-		}
+		q0 = appendwarning(w, warn)
 		t.Show(q0, t.Nc(), true)
 		t.w.SetTag()
 		t.ScrDraw(t.fr.GetFrameFillStatus().Nchars)
@@ -215,7 +248,69 @@ func flushwarnings() {
 			mnt.DecRef(warn.md) // IncRef in addwarningtext
 		}
 	}
-	warnings = warnings[0:0]
+}
+
+// appendwarning inserts the text of warn at the end of the body of w,
+// preceded by a note of how many lines were suppressed, and trims the
+// head of the body to at most *maxErrLines lines. It returns the lowest
+// position changed.
+func appendwarning(w *Window, warn *Warning) int {
+	t := &w.body
+	q0 := t.Nc()
+	if *maxErrLines > 0 {
+		warn.trim(*maxErrLines)
+	}
+	if warn.suppressed > 0 {
+		marker := fmt.Sprintf("... %d lines suppressed ...\n", warn.suppressed)
+		t.Insert(q0, []rune(marker), true)
+	}
+
+	// Most commands don't generate much output. For instance,
+	// Edit ,>cat goes through /dev/cons and is already in blocks
+	// because of the i/o system, but a few can.  Edit ,p will
+	// put the entire result into a single hunk.  So it's worth doing
+	// this in blocks (and putting the text in a buffer in the first
+	// place), to avoid a big memory footprint.
+	r := make([]rune, RBUFSIZE)
+	// TODO(rjk): Figure out why Warning doesn't use a File.
+	for n, nr := 0, 0; n < warn.buf.nc(); n += nr {
+		nr = warn.buf.nc() - n
+		if nr > RBUFSIZE {
+			nr = RBUFSIZE
+		}
+		warn.buf.Read(n, r[:nr])
+		q, _ := w.insertOutput(t, t.Nc(), r[:nr])
+		q0 = min(q0, q) // a carriage return may have erased earlier text
+	}
+	if *maxErrLines > 0 {
+		if q, _ := taillines(t.Nc(), *maxErrLines, t.ReadC); q > 0 {
+			t.Delete(0, q, true)
+			q0 = 0
+		}
+	}
+	return q0
+}
+
+// taillines returns the position at which the last n lines of the nc runes
+// read by readc begin, along with the number of lines before that position.
+// A final line without a newline counts as a line. If there are no more than
+// n lines, taillines returns 0, 0.
+func taillines(nc, n int, readc func(int) rune) (q, nlines int) {
+	for p := nc - 2; p >= 0; p-- {
+		if readc(p) == '\n' {
+			n--
+			if n == 0 {
+				q = p + 1
+				break
+			}
+		}
+	}
+	for p := 0; p < q; p++ {
+		if readc(p) == '\n' {
+			nlines++
+		}
+	}
+	return q, nlines
 }
 
 func warning(md *MntDir, s string, args ...interface{}) {
@@ -229,26 +324,42 @@ func warnError(md *MntDir, s string, args ...interface{}) error {
 	return err
 }
 
+// addwarningtext queues r to be inserted in the +Errors window for md
+// by the next flushwarnings. If there is a limit on the number of lines
+// in +Errors windows, lines beyond the limit are dropped from the head of
+// the queue and counted as suppressed. The queue is trimmed once it holds
+// twice the limit, to keep the cost of a flood linear in its size.
 func addwarningtext(md *MntDir, r []rune) {
 	warningsMu.Lock()
 	defer warningsMu.Unlock()
 
-	for _, warn := range warnings {
-		if warn.md == md {
-			warn.buf.Insert(warn.buf.nc(), r)
-			return
+	var warn *Warning
+	for _, wn := range warnings {
+		if wn.md == md {
+			warn = wn
+			break
 		}
 	}
-	warn := Warning{}
-	warn.md = md
-	if md != nil {
-		mnt.IncRef(md) // DecRef in flushwarnings
+	if warn == nil {
+		warn = &Warning{}
+		warn.md = md
+		if md != nil {
+			mnt.IncRef(md) // DecRef in flushwarnings
+		}
+		warnings = append(warnings, warn)
+		select {
+		case cwarn <- 0:
+		default:
+		}
+	}
+	warn.buf.Insert(warn.buf.nc(), r)
+	for _, c := range r {
+		if c == '\n' {
+			warn.nlines++
+		}
 	}
-	warn.buf.Insert(0, r)
-	warnings = append(warnings, &warn)
-	select {
-	case cwarn <- 0:
-	default:
+	if *maxErrLines > 0 && warn.nlines > 2**maxErrLines {
+		warn.trim(*maxErrLines)
 	}
 }
 
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -92,3 +94,110 @@ func TestSkipbl(t *testing.T) {
 		}
 	}
 }
+
+func TestTaillines(t *testing.T) {
+	tt := []struct {
+		s         string
+		n         int
+		q, nlines int
+	}{
+		{"", 2, 0, 0},
+		{"a\nb\n", 2, 0, 0},
+		{"a\nb", 2, 0, 0},
+		{"a\nb\nc\n", 2, 2, 1},
+		{"a\nb\nc", 2, 2, 1},
+		{"a\nb\nc\nd\n", 1, 6, 3},
+		{"\n\n\n", 1, 2, 2},
+	}
+	for _, tc := range tt {
+		r := []rune(tc.s)
+		q, nlines := taillines(len(r), tc.n, func(p int) rune { return r[p] })
+		if q != tc.q || nlines != tc.nlines {
+			t.Errorf("taillines(%q, %v) returned %v, %v; expected %v, %v",
+				tc.s, tc.n, q, nlines, tc.q, tc.nlines)
+		}
+	}
+}
+
+// setMaxErrLines sets the -E flag to n and returns a function
+// restoring it.
+func setMaxErrLines(n int) (restore func()) {
+	saved := *maxErrLines
+	*maxErrLines = n
+	return func() { *maxErrLines = saved }
+}
+
+func TestAppendwarning(t *testing.T) {
+	defer setMaxErrLines(3)()
+	warnings = nil
+	cwarn = nil
+	defer func() { warnings = nil }()
+
+	for i := 0; i < 5; i++ {
+		addwarningtext(nil, []rune(fmt.Sprintf("line %d\n", i)))
+	}
+	if got, want := len(warnings), 1; got != want {
+		t.Fatalf("got %v warnings; expected %v", got, want)
+	}
+	warn := warnings[0]
+	if got, want := warn.nlines, 5; got != want {
+		t.Errorf("queued %v lines; expected %v", got, want)
+	}
+
+	w := &Window{
+		body: Text{
+			file: &File{
+				b: Buffer("old\n"),
+			},
+		},
+	}
+	w.body.w = w
+	q0 := appendwarning(w, warn)
+	if q0 != 0 {
+		t.Errorf("appendwarning returned %v; expected 0", q0)
+	}
+	want := "line 2\nline 3\nline 4\n"
+	if got := string(w.body.file.b); got != want {
+		t.Errorf("body is %q; expected %q", got, want)
+	}
+
+	*maxErrLines = 0
+	w.body.file.b = Buffer("old\n")
+	q0 = appendwarning(w, warn)
+	if q0 != 4 {
+		t.Errorf("appendwarning returned %v; expected 4", q0)
+	}
+	want = "old\n... 2 lines suppressed ...\nline 2\nline 3\nline 4\n"
+	if got := string(w.body.file.b); got != want {
+		t.Errorf("body is %q; expected %q", got, want)
+	}
+}
+
+// BenchmarkWarningFlood measures the cost of a command flooding cons with
+// small writes, which are coalesced and inserted into +Errors in one go.
+func BenchmarkWarningFlood(b *testing.B) {
+	line := []rune(strings.Repeat("x", 79) + "\n")
+	for _, maxlines := range []int{0, 1000} {
+		b.Run(fmt.Sprintf("maxlines=%v", maxlines), func(b *testing.B) {
+			defer setMaxErrLines(maxlines)()
+			cwarn = nil
+			w := &Window{
+				body: Text{
+					file: &File{},
+				},
+			}
+			w.body.w = w
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				warnings = nil
+				for j := 0; j < 10000; j++ {
+					addwarningtext(nil, line)
+				}
+				appendwarning(w, warnings[0])
+			}
+			b.StopTimer()
+			warnings = nil
+		})
+	}
+}
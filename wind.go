@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/fhs/edward/internal/draw"
+	"github.com/fhs/edward/internal/file"
 	"github.com/fhs/edward/internal/frame"
 )
 
@@ -42,6 +43,7 @@ type Window struct {
 	maxlines    int
 	dirnames    []string
 	widths      []int
+	tree        *dirtree // directory shown as a tree, if set
 	incl        []string
 	ctrllock    sync.Mutex // used for lock/unlock ctl mesage
 	ctlfid      uint32     // ctl file Fid which has the ctrllock
@@ -54,22 +56,30 @@ type Window struct {
 	tagexpand   bool
 	taglines    int
 	tagtop      image.Rectangle
+	deftag      string // default tag text from the bar, while unchanged
+	label       string // title set by the label ctl message
+	title       string // title of the OS window
+	winsize     string // size and position requested for the OS window
+	screen      string // screen or workspace restored from a dump file
 	editoutlk   chan bool
+	ansi        *ansiFilter   // non-nil if escape sequences in output are interpreted
+	isearch     *isearch      // incremental search in progress, if any
+	complete    *completelist // completion list shown, if any
 
 	done        chan struct{} // we close this when the window is closing
 	keyboardctl *draw.Keyboardctl
 	mousectl    *draw.Mousectl
 	mouse       *draw.Mouse // == &mousectl.Mouse
 
-	fontCache map[string]draw.Font
+	fonts *draw.FontCache // shared with other windows if the device allows
 	iconImages
 	scrtmp draw.Image // scroll bar
 }
 
 func NewWindow() *Window {
 	return &Window{
-		done:      make(chan struct{}),
-		fontCache: make(map[string]draw.Font),
+		done:  make(chan struct{}),
+		fonts: draw.NewFontCache(),
 	}
 }
 
@@ -116,6 +126,9 @@ func (w *Window) initHeadless(clone *Window) *Window {
 func (w *Window) Init(clone *Window, r image.Rectangle, dis draw.Display) {
 	w.initHeadless(clone)
 	w.display = dis
+	if drawDev != nil {
+		w.fonts = drawDev.FontCache()
+	}
 	iconinit(dis, &w.iconImages, w.fontget)
 	r1 := r
 
@@ -407,6 +420,7 @@ func (w *Window) Delete() {
 }
 
 func (w *Window) Undo(isundo bool) {
+	w.body.file.loadrest()
 	w.utflastqid = -1
 	body := &w.body
 	if q0, q1, ok := body.file.Undo(isundo); ok {
@@ -422,12 +436,43 @@ func (w *Window) Undo(isundo bool) {
 func (w *Window) SetName(name string) {
 	t := &w.body
 	t.file.SetName(name)
+	t.tabstop, t.tabexpand = tabsfor(name)
 
 	w.SetTag()
 }
 
+// SetTitle updates the title of the OS window showing w. Unless set by a
+// label ctl message, the title shows the file name, whether the file is
+// dirty and the window id, so that window managers can tell windows apart.
+func (w *Window) SetTitle() {
+	if w.display == nil {
+		return
+	}
+	title := w.label
+	if title == "" {
+		dirty := ""
+		if w.body.file.SaveableAndDirty() {
+			dirty = " *"
+		}
+		title = fmt.Sprintf("%s%s [%d]", w.body.file.name, dirty, w.id)
+	}
+	title += " - " + label
+	if title == w.title {
+		return
+	}
+	if err := w.display.SetLabel(title); err != nil {
+		warning(nil, "can't set window title: %v\n", err)
+		return
+	}
+	w.title = title
+}
+
 func (w *Window) Type(t *Text, r rune) {
 	t.Type(r)
+	if t.what == Tag {
+		w.findrefresh()
+	}
+	w.completetyped(t)
 	w.SetTag()
 }
 
@@ -497,9 +542,7 @@ func (w *Window) setTag1() {
 		Lredo     = " Redo"
 		Lget      = " Get"
 		Lput      = " Put"
-		Llook     = " Look"
-		Ledit     = " Edit"
-		Lpipe     = " |"
+		Lreadonly = " [ro]"
 	)
 
 	// (flux) The C implemtation does a lot of work to avoid
@@ -510,6 +553,16 @@ func (w *Window) setTag1() {
 	var sb strings.Builder
 	sb.WriteString(w.body.file.name)
 	sb.WriteString(Ldelsnarf)
+	if w.body.file.ReadOnly() {
+		sb.WriteString(Lreadonly)
+	}
+	if ld := w.body.file.loading; ld != nil {
+		fmt.Fprintf(&sb, " [loading %d%%]", ld.percent())
+	}
+	// Only encodings other than plain UTF-8 are worth pointing out.
+	if enc := w.body.file.Encoding(); enc != (file.Encoding{}) {
+		sb.WriteString(" [" + enc.String() + "]")
+	}
 
 	if w.filemenu {
 		if w.body.needundo || w.body.file.HasUndoableChanges() {
@@ -525,16 +578,17 @@ func (w *Window) setTag1() {
 	if w.body.file.IsDir() {
 		sb.WriteString(Lget)
 	}
+	// The text after the bar follows the window type until the user
+	// changes it.
 	old := w.tag.file.b
 	oldbarIndex := w.tag.file.b.IndexRune('|')
-	if oldbarIndex >= 0 {
+	if oldbarIndex >= 0 && string(old[oldbarIndex:]) != w.deftag {
 		sb.WriteString(" ")
 		sb.WriteString(string(old[oldbarIndex:]))
 	} else {
-		sb.WriteString(Lpipe)
-		sb.WriteString(Llook)
-		sb.WriteString(Ledit)
+		w.deftag = "| " + defaulttag(w) + " "
 		sb.WriteString(" ")
+		sb.WriteString(w.deftag)
 	}
 
 	new := Buffer([]rune(sb.String()))
@@ -573,6 +627,7 @@ func (w *Window) setTag1() {
 	// if we replaced the tag above.
 	w.tag.SetSelect(w.tag.q0, w.tag.q1)
 	w.DrawButton()
+	w.SetTitle()
 	if resize {
 		w.tagsafe = false
 		w.Resize(w.r, true, true)
@@ -675,8 +730,14 @@ func (w *Window) CtlPrint(fonts bool) string {
 		w.body.Nc(), isdir, dirty)
 	if fonts {
 		// fsys exposes the actual physical font name.
-		buf = fmt.Sprintf("%s%11d %s %11d ", buf, w.body.fr.Rect().Dx(),
-			quote(w.fontget(w.body.font).Name()), w.body.fr.GetMaxtab())
+		// The index has fixed-width lines, so the access mode only
+		// appears in the ctl file.
+		mode := "rw"
+		if w.body.file.ReadOnly() {
+			mode = "ro"
+		}
+		buf = fmt.Sprintf("%s%11d %s %11d %s ", buf, w.body.fr.Rect().Dx(),
+			quote(w.fontget(w.body.font).Name()), w.body.fr.GetMaxtab(), mode)
 	}
 	return buf
 }
@@ -718,15 +779,11 @@ func (w *Window) ClampAddr() {
 }
 
 func (w *Window) fontget(name string) draw.Font {
-	if f, ok := w.fontCache[name]; ok {
-		return f
-	}
-	f, err := w.display.OpenFont(name)
+	f, err := w.fonts.Open(w.display, name)
 	if err != nil {
 		warning(nil, "can't open font file %s: %v\n", name, err)
 		return nil
 	}
-	w.fontCache[name] = f
 	return f
 }
 
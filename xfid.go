@@ -25,6 +25,7 @@ var (
 	ErrAddrRange  = fmt.Errorf("address out of range")
 	ErrInUse      = fmt.Errorf("already in use")
 	ErrBadEvent   = fmt.Errorf("bad event syntax")
+	ErrReadOnly   = fmt.Errorf("window is read-only")
 )
 
 func (x *Xfid) respond(t *plan9.Fcall, err error) *Xfid {
@@ -257,7 +258,14 @@ func xfidread(x *Xfid) {
 		fc.Count = 0
 		switch q {
 		case Qcons: // Do nothing.
-		case Qlabel: // Do nothing.
+		case Qlabel:
+			row.lk.Lock()
+			ninep.ReadString(&fc, &x.fcall, label)
+			row.lk.Unlock()
+		case Qsnarf:
+			row.lk.Lock()
+			ninep.ReadString(&fc, &x.fcall, snarfsfile())
+			row.lk.Unlock()
 		case Qindex:
 			xfidindexread(x)
 			return
@@ -287,6 +295,7 @@ func xfidread(x *Xfid) {
 		x.respond(&fc, nil)
 
 	case QWbody:
+		w.body.file.loadrest()
 		xfidutfread(x, &w.body, w.body.Nc(), int(QWbody))
 
 	case QWctl:
@@ -405,6 +414,7 @@ func xfidwrite(x *Xfid) {
 			} else {
 				q0 = t.Nc()
 			}
+			nr := len(r)
 			if qid == QWtag {
 				t.Insert(q0, r, true)
 			} else {
@@ -412,8 +422,8 @@ func xfidwrite(x *Xfid) {
 					seq++
 					t.file.Mark(seq)
 				}
-				q, nr := t.BsInsert(q0, r, true) // TODO(flux): BsInsert returns nr?
-				q0 = q
+				// Filtering can leave fewer runes than were written.
+				q0, nr = w.insertOutput(t, q0, r)
 				t.SetSelect(t.q0, t.q1) // insert could leave it somewhere else
 				if qid != QWwrsel && shouldscroll(t, q0, qid) {
 					t.Show(q0+(nr), q0+(nr), true)
@@ -422,7 +432,7 @@ func xfidwrite(x *Xfid) {
 			}
 			w.SetTag()
 			if qid == QWwrsel {
-				w.wrselrange.q1 += len(r)
+				w.wrselrange.q1 = q0 + nr
 			}
 		}
 		fc.Count = x.fcall.Count
@@ -432,10 +442,33 @@ func xfidwrite(x *Xfid) {
 	//x.fcall.Data[x.fcall.Count] = 0; // null-terminate. unneeded
 	switch qid {
 	case Qcons:
-		w = errorwin(x.f.mntdir, 'X')
-		updateText(&w.body)
+		// Queue the text instead of inserting it right away, so that
+		// a flood of writes is coalesced by flushwarnings.
+		addwarningtext(x.f.mntdir, fullrunewrite(x))
+		fc.Count = x.fcall.Count
+		x.respond(&fc, nil)
 
 	case Qlabel:
+		// Set the name that ends every window title.
+		row.lk.Lock()
+		label = strings.TrimRight(string(x.fcall.Data), "\n")
+		for _, w := range row.col.w {
+			w.Lock('F')
+			w.SetTitle()
+			w.Unlock()
+		}
+		row.lk.Unlock()
+		fc.Count = x.fcall.Count
+		x.respond(&fc, nil)
+
+	case Qsnarf:
+		row.lk.Lock()
+		err := writesnarfsfile(x.fcall.Offset, int(x.fcall.Count), fullrunewrite(x))
+		row.lk.Unlock()
+		if err != nil {
+			x.respond(&fc, err)
+			return
+		}
 		fc.Count = x.fcall.Count
 		x.respond(&fc, nil)
 
@@ -443,6 +476,7 @@ func xfidwrite(x *Xfid) {
 		r := []rune(string(x.fcall.Data))
 		t := &w.body
 		w.Commit(t)
+		t.file.loadrest()
 		eval := true
 		a, eval, nr := address(false, t, w.limit, w.addr, 0, len(r),
 			func(q int) rune { return r[q] }, eval)
@@ -478,12 +512,20 @@ func xfidwrite(x *Xfid) {
 		updateText(&w.body)
 
 	case QWbody, QWwrsel:
+		if w.body.file.ReadOnly() {
+			x.respond(&fc, ErrReadOnly)
+			break
+		}
 		updateText(&w.body)
 
 	case QWctl:
 		xfidctlwrite(x, w)
 
 	case QWdata:
+		if w.body.file.ReadOnly() {
+			x.respond(&fc, ErrReadOnly)
+			break
+		}
 		a := w.addr
 		t := &w.body
 		w.Commit(t)
@@ -583,6 +625,25 @@ forloop:
 			// doesn't change sequence number, so "Put" won't appear.  it shouldn't.
 			t.file.Modded()
 			settag = true
+		case "ro": // refuse edits to the body
+			w.body.file.SetReadOnly(true)
+			settag = true
+		case "rw": // allow edits to the body, even if the file can't be written
+			w.body.file.SetReadOnly(false)
+			settag = true
+		case "encoding": // set encoding of the disk file
+			if len(words) < 2 {
+				err = ErrBadCtl
+				break forloop
+			}
+			enc, e := w.body.file.Encoding().Parse(words[1])
+			if e == nil {
+				e = setencoding(w, enc)
+			}
+			if e != nil {
+				err = e
+				break forloop
+			}
 		case "show": // show dot
 			t := &w.body
 			t.Show(t.q0, t.q1, true)
@@ -662,6 +723,12 @@ forloop:
 			seq++
 			w.body.file.Mark(seq)
 			settag = true
+		case "noansi": // insert escape sequences in output as is
+			w.ansi = nil
+		case "ansi": // interpret escape sequences in output
+			if w.ansi == nil {
+				w.ansi = &ansiFilter{}
+			}
 		case "nomenu": // turn off automatic menu
 			w.filemenu = false
 		case "menu": // enable automatic menu
@@ -669,6 +736,17 @@ forloop:
 		case "cleartag": // wipe tag right of bar
 			w.ClearTag()
 			settag = true
+		case "label": // set window title; reset it if none given
+			w.label = ""
+			if len(words) > 1 {
+				r, _, nulls := cvttorunes([]byte(words[1]), len(words[1]))
+				if nulls {
+					err = fmt.Errorf("nulls in label")
+					break forloop
+				}
+				w.label = string(r)
+			}
+			w.SetTitle()
 		case "font":
 			if len(words) < 2 {
 				err = ErrBadCtl
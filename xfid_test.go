@@ -705,7 +705,78 @@ func TestXfidwriteQWwrsel(t *testing.T) {
 	}
 }
 
+func TestXfidwriteReadOnly(t *testing.T) {
+	w := NewWindow().initHeadless(nil)
+	w.col = new(Column)
+	w.body.file.b = Buffer("Hello, world!\n")
+	w.body.file.SetReadOnly(true)
+
+	for _, q := range []uint64{QWbody, QWwrsel, QWdata} {
+		data := []byte("more")
+		mr := new(mockResponder)
+		xfidwrite(&Xfid{
+			fcall: plan9.Fcall{
+				Data:  data,
+				Count: uint32(len(data)),
+			},
+			f: &Fid{
+				qid: plan9.Qid{Path: QID(0, q)},
+				w:   w,
+			},
+			fs: mr,
+		})
+		if mr.err != ErrReadOnly {
+			t.Errorf("write to qid %v returned error %v; want %v", q, mr.err, ErrReadOnly)
+		}
+		if got, want := string(w.body.file.b), "Hello, world!\n"; got != want {
+			t.Errorf("write to qid %v changed body to %q; want %q", q, got, want)
+		}
+	}
+}
+
+func TestXfidwriteQWwrselANSI(t *testing.T) {
+	w := NewWindow().initHeadless(nil)
+	w.display = edwoodtest.NewDisplay()
+	w.col = new(Column)
+	w.body.fr = &MockFrame{}
+	w.body.file.b = Buffer("ab")
+	w.ansi = &ansiFilter{}
+	w.wrselrange = Range{1, 1}
+
+	data := []byte("\x1b[1mxyz\x1b[0m")
+	mr := new(mockResponder)
+	xfidwrite(&Xfid{
+		fcall: plan9.Fcall{
+			Data:  data,
+			Count: uint32(len(data)),
+		},
+		f: &Fid{
+			qid: plan9.Qid{Path: QID(0, QWwrsel)},
+			w:   w,
+		},
+		fs: mr,
+	})
+	if mr.err != nil {
+		t.Fatalf("got error %v; want nil", mr.err)
+	}
+	if got, want := string(w.body.file.b), "axyzb"; got != want {
+		t.Errorf("buffer is %q; want %q", got, want)
+	}
+	if got, want := w.wrselrange, (Range{1, 4}); got != want {
+		t.Errorf("wrselrange is %v; want %v", got, want)
+	}
+}
+
 func TestXfidwriteQlabel(t *testing.T) {
+	defer func(l string) { label = l }(label)
+
+	display := edwoodtest.NewDisplay()
+	w := NewWindow().initHeadless(nil)
+	w.display = display
+	w.body.file.name = "/a/file"
+	w.body.file.seq = 1
+	row = Row{col: Column{w: []*Window{w}}}
+
 	data := []byte("Hello, 世界!\n")
 	mr := new(mockResponder)
 	x := &Xfid{
@@ -725,6 +796,18 @@ func TestXfidwriteQlabel(t *testing.T) {
 	if got, want := mr.fcall.Count, uint32(len(data)); got != want {
 		t.Errorf("fcall.Count is %v; want %v", got, want)
 	}
+	want := fmt.Sprintf("/a/file * [%d] - Hello, 世界!", w.id)
+	if got := display.(interface{ Label() string }).Label(); got != want {
+		t.Errorf("window title is %q; want %q", got, want)
+	}
+
+	mr = new(mockResponder)
+	x.fcall = plan9.Fcall{Count: 100}
+	x.fs = mr
+	xfidread(x)
+	if got, want := string(mr.fcall.Data), "Hello, 世界!"; got != want {
+		t.Errorf("read label %q; want %q", got, want)
+	}
 }
 
 func TestXfidwriteQcons(t *testing.T) {
@@ -862,6 +945,11 @@ func TestXfidwriteQWctl(t *testing.T) {
 		{nil, "clean"},
 		{nil, "clean\n"},
 		{nil, "dirty"},
+		{nil, "ro"},
+		{nil, "rw"},
+		{nil, "encoding latin1 crlf"},
+		{ErrBadCtl, "encoding"},
+		{fmt.Errorf("unknown encoding \"koi8\""), "encoding koi8"},
 		{nil, "show"},
 		{ErrBadCtl, "name"},
 		{nil, "name /Test/Write/Ctl"},
@@ -885,6 +973,11 @@ func TestXfidwriteQWctl(t *testing.T) {
 		{nil, "mark"},
 		{nil, "nomenu"},
 		{nil, "menu"},
+		{nil, "ansi"},
+		{nil, "noansi"},
+		{nil, "label my window"},
+		{nil, "label"},
+		{fmt.Errorf("nulls in label"), "label a\u0000b"},
 		{nil, "cleartag"},
 		{ErrBadCtl, "brewcoffee"},
 		{ErrDeletedWin, "delete\nclean"},
@@ -1047,7 +1140,6 @@ func TestXfidreadEmptyFiles(t *testing.T) {
 		q    uint64
 	}{
 		{"Qcons", Qcons},
-		{"Qlabel", Qlabel},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			mr := new(mockResponder)
@@ -1247,7 +1339,7 @@ func TestXfidreadQWaddr(t *testing.T) {
 }
 
 func TestXfidreadQWctl(t *testing.T) {
-	const want = "          1          32          14           0           0           0 /lib/font/edwood.font           0 "
+	const want = "          1          32          14           0           0           0 /lib/font/edwood.font           0 rw "
 
 	WinID = 0
 	w := NewWindow().initHeadless(nil)